@@ -2,23 +2,55 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/zahartd/biathlon_competitions_system/internal/config"
 	"github.com/zahartd/biathlon_competitions_system/internal/engine"
 	"github.com/zahartd/biathlon_competitions_system/internal/events"
+	"github.com/zahartd/biathlon_competitions_system/internal/models"
 	"github.com/zahartd/biathlon_competitions_system/internal/output"
+	"github.com/zahartd/biathlon_competitions_system/internal/output/html"
+	"github.com/zahartd/biathlon_competitions_system/internal/roster"
+	"github.com/zahartd/biathlon_competitions_system/internal/server"
 )
 
+// tailInterval is how often a followed events file is polled for new lines.
+const tailInterval = 200 * time.Millisecond
+
+// liveHistory is how many latest events push clients can resume from.
+const liveHistory = 10000
+
 func main() {
 	cfgPath := flag.String("config", "", "path to JSON config")
-	eventsPath := flag.String("events", "", "path to incoming events")
+	eventsPath := flag.String("events", "", "path to incoming events, - for stdin")
 	outlogPath := flag.String("out", "", "path to output log")
+	jsonlPath := flag.String("jsonl", "", "path to an additional JSON Lines output log")
 	verbose := flag.Bool("v", false, "verbose output")
+	order := flag.String("order", "start", "report order: start (start list) or result (places)")
+	previousPath := flag.String("previous", "", "path to the previous race report for pursuit or super sprint final start lists")
+	format := flag.String("format", "text", "report format: text, json, csv or html")
+	splits := flag.Bool("splits", false, "text report: break laps into course, range and penalty time")
+	csvLayout := flag.String("csv-layout", "wide", "CSV layout: wide (row per competitor) or long (row per lap)")
+	strict := flag.Bool("strict", false, "fail on events that break the competitor lifecycle")
+	follow := flag.Bool("follow", false, "follow the events file as it grows until the race is closed, Enter prints the report")
+	standingsAt := flag.String("at", "", "print the standings at the clock time HH:MM:SS.sss instead of the report")
+	checkpoint := flag.String("checkpoint", "", "print the standings after lap:N or stage:K instead of the report")
+	serveAddr := flag.String("serve", "", "serve live results over HTTP on the address instead of printing a report")
+	rosterPath := flag.String("roster", "", "path to the athlete roster, CSV or JSON")
+	nation := flag.String("nation", "", "report only competitors of the roster nation")
+	category := flag.String("category", "", "report only competitors of the roster category")
+	groupBy := flag.String("group", "", "group the report by roster nation or category")
 	flag.Parse()
 
 	out := io.Discard
@@ -27,20 +59,76 @@ func main() {
 	}
 	verboseLogger := log.New(out, "VERBOSE: ", log.LstdFlags)
 
-	cfg, err := config.Load(cfgPath)
-	if err != nil {
-		log.Fatalf("Failed to load configs: %s", err.Error())
+	if *order != "start" && *order != "result" {
+		log.Printf("Unknown report order %q", *order)
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *format != "text" && *format != "json" && *format != "csv" && *format != "html" {
+		log.Printf("Unknown report format %q", *format)
+		flag.Usage()
+		os.Exit(1)
+	}
+	if *csvLayout != string(engine.CSVWide) && *csvLayout != string(engine.CSVLong) {
+		log.Printf("Unknown CSV layout %q", *csvLayout)
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	eventsFile, err := os.Open(*eventsPath)
+	if *groupBy != "" && *groupBy != string(engine.GroupByNation) && *groupBy != string(engine.GroupByCategory) {
+		log.Printf("Unknown report grouping %q", *groupBy)
+		flag.Usage()
+		os.Exit(1)
+	}
+	if (*nation != "" || *category != "" || *groupBy != "") && *rosterPath == "" {
+		log.Print("Filtering and grouping the report need a roster")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if *follow && (*standingsAt != "" || *checkpoint != "") {
+		log.Print("Standings at a time or checkpoint can`t be followed, drop -follow")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	var at time.Time
+	if *standingsAt != "" {
+		var err error
+		if at, err = time.Parse(events.TimeLayoutHMSMilli, *standingsAt); err != nil {
+			log.Printf("Invalid standings time %q, expected %s", *standingsAt, events.TimeLayoutHMSMilli)
+			flag.Usage()
+			os.Exit(1)
+		}
+	}
+	var cp engine.Checkpoint
+	if *checkpoint != "" {
+		var err error
+		if cp, err = engine.ParseCheckpoint(*checkpoint); err != nil {
+			log.Print(err)
+			flag.Usage()
+			os.Exit(1)
+		}
+	}
+
+	cfg, err := config.Load(cfgPath)
 	if err != nil {
-		log.Fatalf("Failed to load events: %s", err.Error())
+		log.Fatalf("Failed to load configs: %s", err.Error())
 		flag.Usage()
 		os.Exit(1)
 	}
-	defer eventsFile.Close()
+
+	// a live server may get all events over HTTP
+	var eventsFile *os.File
+	if *serveAddr == "" || *eventsPath != "" {
+		eventsFile, err = openEvents(*eventsPath)
+		if err != nil {
+			log.Fatalf("Failed to load events: %s", err.Error())
+			flag.Usage()
+			os.Exit(1)
+		}
+		defer eventsFile.Close()
+	}
 
 	outlogFile, err := os.OpenFile(
 		*outlogPath,
@@ -54,13 +142,60 @@ func main() {
 	}
 	defer outlogFile.Close()
 
+	var athletes models.Roster
+	if *rosterPath != "" {
+		athletes, err = roster.Load(*rosterPath)
+		if err != nil {
+			log.Fatalf("Failed to load roster: %s", err.Error())
+		}
+	}
+
 	eventParser := events.NewParser()
-	resultLogger := output.NewLogger(outlogFile)
-	eventEngine := engine.NewEngine(cfg, resultLogger)
+	var loggerOpts []output.LoggerOption
+	if athletes != nil {
+		loggerOpts = append(loggerOpts, output.WithRoster(athletes))
+	}
+	sinks := []output.EventSink{output.NewLogger(outlogFile, loggerOpts...)}
+	if *jsonlPath != "" {
+		jsonlFile, err := os.OpenFile(*jsonlPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+		if err != nil {
+			log.Fatalf("Incorrect JSON Lines log: %s", err.Error())
+		}
+		defer jsonlFile.Close()
+		sinks = append(sinks, output.NewJSONLogger(jsonlFile))
+	}
+	mode := engine.ModeLenient
+	if *strict {
+		mode = engine.ModeStrict
+	}
+	opts := []engine.Option{engine.WithMode(mode)}
+	if athletes != nil {
+		opts = append(opts, engine.WithRoster(athletes))
+	}
+	starts, err := engine.StartListFor(cfg, *previousPath)
+	if err != nil {
+		log.Fatalf("Failed to load previous race report: %s", err.Error())
+	}
+	if starts != nil {
+		opts = append(opts, engine.WithStartList(starts))
+	}
+	var eventLog output.MemorySink // output events for the HTML report
+	if *format == "html" {
+		sinks = append(sinks, &eventLog)
+	}
+	var liveLog *output.MemorySink
+	var hub *server.Hub
+	if *serveAddr != "" {
+		liveLog, hub = &output.MemorySink{}, server.NewHub(liveHistory)
+		sinks = append(sinks, liveLog, hub)
+	}
+	eventEngine := engine.NewEngine(cfg, output.NewMultiSink(sinks...), opts...)
+	if *serveAddr != "" {
+		serve(*serveAddr, server.New(eventEngine, liveLog, hub), eventsFile)
+		return
+	}
 
-	scanner := bufio.NewScanner(eventsFile)
-	for scanner.Scan() {
-		line := scanner.Text()
+	processLine := func(line string) {
 		verboseLogger.Printf("Parsed line: %s", line)
 		event, err := eventParser.ParseEvent(line)
 		if err != nil {
@@ -76,13 +211,223 @@ func main() {
 		}
 		verboseLogger.Printf("Processed event: %v", event)
 	}
+	printReport := func() {
+		rows := eventEngine.GetReport()
+		if *order == "result" {
+			engine.SortByResult(rows)
+		}
+		rows = engine.RowFilter{Nation: *nation, Category: *category}.Apply(rows)
+		var err error
+		if *groupBy != "" {
+			err = writeGroupedReport(os.Stdout, rows, engine.GroupBy(*groupBy), cfg, *order, *format, *splits, engine.CSVLayout(*csvLayout), eventLog.Events())
+		} else {
+			err = writeReport(os.Stdout, rows, cfg, *order, *format, *splits, engine.CSVLayout(*csvLayout), eventLog.Events())
+		}
+		if err != nil {
+			log.Fatalf("Failed to write report: %s", err.Error())
+		}
+	}
+
+	if *follow {
+		followEvents(eventsFile, processLine, printReport, eventEngine.Closed)
+		eventEngine.Finalize()
+		printReport()
+		return
+	}
+
+	scanner := bufio.NewScanner(eventsFile)
+	for scanner.Scan() {
+		processLine(scanner.Text())
+	}
 	if err := scanner.Err(); err != nil {
 		log.Fatalf("Failed to reading events: %s", err.Error())
 	}
 
 	eventEngine.Finalize()
-	rows := eventEngine.GetReport()
+	switch {
+	case *standingsAt != "":
+		printStandings(eventEngine.StandingsAt(at))
+	case *checkpoint != "":
+		printStandings(eventEngine.StandingsAfter(cp))
+	default:
+		printReport()
+	}
+}
+
+func printStandings(standings []engine.Standing) {
+	for _, s := range standings {
+		fmt.Fprint(os.Stdout, s.Format())
+	}
+}
+
+func writeReport(
+	w io.Writer,
+	rows []engine.ReportRow,
+	cfg config.Config,
+	order string,
+	format string,
+	splits bool,
+	csvLayout engine.CSVLayout,
+	eventLog []models.Event,
+) error {
+	switch format {
+	case "json":
+		return engine.WriteJSON(w, rows)
+	case "html":
+		title := fmt.Sprintf("Biathlon results: %s", cfg.RaceType)
+		return html.Render(w, title, rows, eventLog)
+	case "csv":
+		return engine.WriteCSV(w, rows, cfg, csvLayout)
+	}
 	for _, r := range rows {
-		fmt.Fprint(os.Stdout, r.Format())
+		line := r.Format()
+		if order == "result" {
+			line = r.FormatResult()
+		}
+		if splits {
+			line += r.FormatSplits()
+		}
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeGroupedReport writes the text report group by group under a header
+// line naming the group. Other formats get the rows reordered group by group.
+func writeGroupedReport(
+	w io.Writer,
+	rows []engine.ReportRow,
+	by engine.GroupBy,
+	cfg config.Config,
+	order string,
+	format string,
+	splits bool,
+	csvLayout engine.CSVLayout,
+	eventLog []models.Event,
+) error {
+	groups, err := engine.GroupRows(rows, by)
+	if err != nil {
+		return err
+	}
+	if format != "text" {
+		var grouped []engine.ReportRow
+		for _, group := range groups {
+			grouped = append(grouped, group.Rows...)
+		}
+		return writeReport(w, grouped, cfg, order, format, splits, csvLayout, eventLog)
+	}
+	for _, group := range groups {
+		key := group.Key
+		if key == "" {
+			key = fmt.Sprintf("(no %s)", by)
+		}
+		if _, err := fmt.Fprintf(w, "%s:\n", key); err != nil {
+			return err
+		}
+		if err := writeReport(w, group.Rows, cfg, order, format, splits, csvLayout, eventLog); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// followEvents processes events as they are appended to the file, like
+// `tail -f`. The report is printed on every report signal and, unless events
+// come from stdin, on every Enter. It returns when the race is closed, a
+// piped input ends or the program is interrupted.
+func followEvents(eventsFile *os.File, processLine func(string), printReport func(), closed func() bool) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	reportRequests := make(chan os.Signal, 1)
+	if sigs := reportSignals(); len(sigs) > 0 {
+		signal.Notify(reportRequests, sigs...)
+		defer signal.Stop(reportRequests)
+	}
+	keyRequests := make(chan struct{}, 1)
+	if eventsFile != os.Stdin {
+		go readKeys(keyRequests)
+	}
+
+	// only a regular file can grow, a pipe is over at its end
+	interval := tailInterval
+	if info, err := eventsFile.Stat(); err == nil && !info.Mode().IsRegular() {
+		interval = 0
+	}
+
+	lines := make(chan string)
+	tailDone := make(chan error, 1)
+	go func() {
+		tailDone <- events.Tail(ctx, eventsFile, interval, func(line string) error {
+			select {
+			case lines <- line:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	for {
+		select {
+		case line := <-lines:
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			processLine(line)
+			if closed() {
+				return
+			}
+		case <-reportRequests:
+			printReport()
+		case <-keyRequests:
+			printReport()
+		case err := <-tailDone:
+			if err != nil && !errors.Is(err, context.Canceled) {
+				log.Fatalf("Failed to reading events: %s", err.Error())
+			}
+			return
+		}
+	}
+}
+
+// readKeys requests a report for every line typed on stdin.
+func readKeys(requests chan<- struct{}) {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		select {
+		case requests <- struct{}{}:
+		default: // a report is already due
+		}
+	}
+}
+
+// serve runs the live results server. Events come from POST requests and,
+// if given, from the events file tailed as it grows.
+func serve(addr string, srv *server.Server, eventsFile *os.File) {
+	if eventsFile != nil {
+		go func() {
+			err := events.Tail(context.Background(), eventsFile, tailInterval, func(line string) error {
+				if strings.TrimSpace(line) == "" {
+					return nil
+				}
+				if err := srv.ProcessLine(line); err != nil {
+					log.Printf("Skipping event %s: %s", line, err)
+				}
+				return nil
+			})
+			log.Printf("Stopped reading events: %s", err)
+		}()
+	}
+	log.Printf("Serving live results on %s", addr)
+	log.Fatal(http.ListenAndServe(addr, srv.Handler()))
+}
+
+func openEvents(path string) (*os.File, error) {
+	if path == "-" {
+		return os.Stdin, nil
 	}
+	return os.Open(path)
 }
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// reportSignals are the signals that print the current report in follow mode.
+// Only unix systems have a spare signal for it.
+func reportSignals() []os.Signal {
+	return nil
+}
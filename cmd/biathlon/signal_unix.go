@@ -0,0 +1,13 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// reportSignals are the signals that print the current report in follow mode.
+func reportSignals() []os.Signal {
+	return []os.Signal{syscall.SIGUSR1}
+}
@@ -0,0 +1,109 @@
+// Command shooting prints shooting statistics of one race or aggregated over
+// several races run with the same config. Competitors are told apart by
+// their ID only, so the IDs must stand for the same athletes in every race.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/analytics"
+	"github.com/zahartd/biathlon_competitions_system/internal/config"
+	"github.com/zahartd/biathlon_competitions_system/internal/engine"
+	"github.com/zahartd/biathlon_competitions_system/internal/events"
+	"github.com/zahartd/biathlon_competitions_system/internal/output"
+)
+
+func main() {
+	cfgPath := flag.String("config", "", "path to JSON config")
+	format := flag.String("format", "table", "statistics format: table or json")
+	strict := flag.Bool("strict", false, "fail on events that break the competitor lifecycle")
+	previousPath := flag.String("previous", "", "path to the previous race report for pursuit or super sprint final start lists")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s -config config.json [flags] events...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *format != "table" && *format != "json" {
+		log.Printf("Unknown statistics format %q", *format)
+		flag.Usage()
+		os.Exit(1)
+	}
+	if flag.NArg() == 0 {
+		log.Print("No events files")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load(cfgPath)
+	if err != nil {
+		log.Fatalf("Failed to load configs: %s", err.Error())
+	}
+	opts := []engine.Option{engine.WithMode(engine.ModeLenient)}
+	if *strict {
+		opts = []engine.Option{engine.WithMode(engine.ModeStrict)}
+	}
+	starts, err := engine.StartListFor(cfg, *previousPath)
+	if err != nil {
+		log.Fatalf("Failed to load previous race report: %s", err.Error())
+	}
+	if starts != nil {
+		// the start list belongs to a single race
+		if flag.NArg() > 1 {
+			log.Printf("A %s start list comes from -previous, pass a single events file", cfg.RaceType)
+			flag.Usage()
+			os.Exit(1)
+		}
+		opts = append(opts, engine.WithStartList(starts))
+	}
+
+	collector := analytics.NewCollector()
+	for _, path := range flag.Args() {
+		stages, err := runRace(cfg, path, opts...)
+		if err != nil {
+			log.Fatalf("Failed to process events %s: %s", path, err.Error())
+		}
+		collector.Add(stages)
+	}
+
+	write := analytics.WriteTable
+	if *format == "json" {
+		write = analytics.WriteJSON
+	}
+	if err := write(os.Stdout, collector.Stats()); err != nil {
+		log.Fatalf("Failed to write statistics: %s", err.Error())
+	}
+}
+
+// runRace processes the events of one race and returns its shooting stages.
+// The output log is discarded.
+func runRace(cfg config.Config, path string, opts ...engine.Option) ([]engine.ShootingStage, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	parser := events.NewParser()
+	e := engine.NewEngine(cfg, output.NewLogger(io.Discard), opts...)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		event, err := parser.ParseEvent(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse event %s: %w", scanner.Text(), err)
+		}
+		if err := e.ProcessEvent(event); err != nil {
+			return nil, fmt.Errorf("failed to process event %v: %w", event, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	e.Finalize()
+	return e.ShootingStages(), nil
+}
@@ -0,0 +1,238 @@
+// Package analytics computes shooting statistics from the firing range
+// visits of one or more races.
+package analytics
+
+import (
+	"sort"
+	"time"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/config"
+	"github.com/zahartd/biathlon_competitions_system/internal/engine"
+)
+
+// Shooting sums up a set of firing range visits.
+type Shooting struct {
+	Stages      int // firing range visits
+	Hits        int
+	Shots       int
+	FirstHit    time.Duration // mean time from range entry to the first hit
+	BetweenHits time.Duration // mean time between consecutive hits
+}
+
+// Accuracy returns the share of shots that hit, 0 without shots.
+func (s Shooting) Accuracy() float64 {
+	if s.Shots == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Shots)
+}
+
+// AthleteStage is the shooting of a competitor at one stage over all races.
+type AthleteStage struct {
+	CompetitorID int
+	Stage        int
+	Shooting
+}
+
+// RangeStats is the field-wide shooting on one firing range.
+type RangeStats struct {
+	Range int
+	Shooting
+}
+
+// PositionStats is the field-wide shooting in one position.
+type PositionStats struct {
+	Position config.Position
+	Shooting
+}
+
+// AthletePosition is the shooting of a competitor in one position over all
+// races.
+type AthletePosition struct {
+	CompetitorID int
+	Position     config.Position
+	Shooting
+}
+
+// TargetStats counts targets left standing after a visit.
+type TargetStats struct {
+	Target int // target number, starting at 1
+	Stages int
+	Misses int
+}
+
+// MissRate returns the share of visits the target was left standing.
+func (t TargetStats) MissRate() float64 {
+	if t.Stages == 0 {
+		return 0
+	}
+	return float64(t.Misses) / float64(t.Stages)
+}
+
+// Stats holds the shooting statistics of the collected races.
+type Stats struct {
+	Races    int
+	Total    Shooting
+	Athletes []AthleteStage // by competitor, then by stage
+	Ranges   []RangeStats   // by range number
+	Targets  []TargetStats  // by target number
+
+	// empty unless the races configure shooting positions
+	Positions        []PositionStats   // prone first
+	AthletePositions []AthletePosition // by competitor, prone first
+}
+
+// tally accumulates visits for a Shooting.
+type tally struct {
+	stages, hits, shots int
+	firstHit            time.Duration
+	firstHits           int
+	between             time.Duration
+	intervals           int
+}
+
+func (t *tally) add(stage engine.ShootingStage) {
+	t.stages++
+	t.hits += stage.Hits
+	t.shots += stage.Shots
+	if len(stage.HitTimes) == 0 {
+		return
+	}
+	t.firstHit += stage.HitTimes[0].Sub(stage.Enter)
+	t.firstHits++
+	for i := 1; i < len(stage.HitTimes); i++ {
+		t.between += stage.HitTimes[i].Sub(stage.HitTimes[i-1])
+		t.intervals++
+	}
+}
+
+func (t *tally) shooting() Shooting {
+	s := Shooting{Stages: t.stages, Hits: t.hits, Shots: t.shots}
+	if t.firstHits > 0 {
+		s.FirstHit = t.firstHit / time.Duration(t.firstHits)
+	}
+	if t.intervals > 0 {
+		s.BetweenHits = t.between / time.Duration(t.intervals)
+	}
+	return s
+}
+
+type athleteKey struct {
+	competitorID int
+	stage        int
+}
+
+type athletePositionKey struct {
+	competitorID int
+	position     config.Position
+}
+
+// Collector aggregates the shooting stages of races added one by one.
+type Collector struct {
+	races    int
+	total    tally
+	athletes map[athleteKey]*tally
+	ranges   map[int]*tally
+	targets  []TargetStats
+
+	positions        map[config.Position]*tally
+	athletePositions map[athletePositionKey]*tally
+}
+
+func NewCollector() *Collector {
+	return &Collector{
+		athletes: make(map[athleteKey]*tally),
+		ranges:   make(map[int]*tally),
+
+		positions:        make(map[config.Position]*tally),
+		athletePositions: make(map[athletePositionKey]*tally),
+	}
+}
+
+// Add collects the shooting stages of one race.
+func (c *Collector) Add(stages []engine.ShootingStage) {
+	c.races++
+	for _, stage := range stages {
+		c.total.add(stage)
+		tallyOf(c.athletes, athleteKey{competitorID: stage.CompetitorID, stage: stage.Stage}).add(stage)
+		tallyOf(c.ranges, stage.Range).add(stage)
+		if stage.Position != "" {
+			tallyOf(c.positions, stage.Position).add(stage)
+			tallyOf(c.athletePositions, athletePositionKey{competitorID: stage.CompetitorID, position: stage.Position}).add(stage)
+		}
+
+		for i, down := range stage.Targets {
+			for len(c.targets) <= i {
+				c.targets = append(c.targets, TargetStats{Target: len(c.targets) + 1})
+			}
+			c.targets[i].Stages++
+			if !down {
+				c.targets[i].Misses++
+			}
+		}
+	}
+}
+
+// tallyOf returns the tally of the key, adding an empty one first.
+func tallyOf[K comparable](tallies map[K]*tally, key K) *tally {
+	if tallies[key] == nil {
+		tallies[key] = &tally{}
+	}
+	return tallies[key]
+}
+
+// Stats returns the statistics of the races added so far.
+func (c *Collector) Stats() Stats {
+	stats := Stats{
+		Races:   c.races,
+		Total:   c.total.shooting(),
+		Targets: append([]TargetStats(nil), c.targets...),
+	}
+	for key, t := range c.athletes {
+		stats.Athletes = append(stats.Athletes, AthleteStage{
+			CompetitorID: key.competitorID,
+			Stage:        key.stage,
+			Shooting:     t.shooting(),
+		})
+	}
+	sort.Slice(stats.Athletes, func(i, j int) bool {
+		a, b := stats.Athletes[i], stats.Athletes[j]
+		if a.CompetitorID != b.CompetitorID {
+			return a.CompetitorID < b.CompetitorID
+		}
+		return a.Stage < b.Stage
+	})
+	for rangeNum, t := range c.ranges {
+		stats.Ranges = append(stats.Ranges, RangeStats{Range: rangeNum, Shooting: t.shooting()})
+	}
+	sort.Slice(stats.Ranges, func(i, j int) bool {
+		return stats.Ranges[i].Range < stats.Ranges[j].Range
+	})
+
+	for position, t := range c.positions {
+		stats.Positions = append(stats.Positions, PositionStats{Position: position, Shooting: t.shooting()})
+	}
+	sort.Slice(stats.Positions, func(i, j int) bool {
+		return positionLess(stats.Positions[i].Position, stats.Positions[j].Position)
+	})
+	for key, t := range c.athletePositions {
+		stats.AthletePositions = append(stats.AthletePositions, AthletePosition{
+			CompetitorID: key.competitorID,
+			Position:     key.position,
+			Shooting:     t.shooting(),
+		})
+	}
+	sort.Slice(stats.AthletePositions, func(i, j int) bool {
+		a, b := stats.AthletePositions[i], stats.AthletePositions[j]
+		if a.CompetitorID != b.CompetitorID {
+			return a.CompetitorID < b.CompetitorID
+		}
+		return positionLess(a.Position, b.Position)
+	})
+	return stats
+}
+
+// positionLess orders prone before standing.
+func positionLess(a, b config.Position) bool {
+	return a == config.PositionProne && b != config.PositionProne
+}
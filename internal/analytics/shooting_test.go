@@ -0,0 +1,161 @@
+package analytics
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/config"
+	"github.com/zahartd/biathlon_competitions_system/internal/engine"
+)
+
+func clock(s string) time.Time {
+	t, err := time.Parse("15:04:05.000", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func stage(competitorID, stageNum, rangeNum int, enter string, targets [5]bool, hits ...string) engine.ShootingStage {
+	s := engine.ShootingStage{
+		CompetitorID: competitorID,
+		Stage:        stageNum,
+		Range:        rangeNum,
+		Enter:        clock(enter),
+		Targets:      targets,
+		Shots:        5,
+	}
+	for _, hit := range hits {
+		s.HitTimes = append(s.HitTimes, clock(hit))
+	}
+	s.Hits = len(hits)
+	return s
+}
+
+func testStats() Stats {
+	c := NewCollector()
+	c.Add([]engine.ShootingStage{
+		stage(1, 1, 1, "10:00:00.000", [5]bool{true, true, true, true, true},
+			"10:00:20.000", "10:00:23.000", "10:00:26.000", "10:00:29.000", "10:00:32.000"),
+		stage(2, 1, 1, "10:00:10.000", [5]bool{true, false, true, false, true},
+			"10:00:40.000", "10:00:50.000", "10:01:00.000"),
+	})
+	c.Add([]engine.ShootingStage{
+		stage(1, 1, 2, "11:00:00.000", [5]bool{false, true, true, true, true},
+			"11:00:30.000", "11:00:35.000", "11:00:40.000", "11:00:45.000"),
+		stage(1, 2, 2, "11:05:00.000", [5]bool{}),
+	})
+	return c.Stats()
+}
+
+func TestCollector(t *testing.T) {
+	stats := testStats()
+
+	assert.Equal(t, 2, stats.Races)
+	assert.Equal(t, Shooting{Stages: 4, Hits: 12, Shots: 20, FirstHit: 80 * time.Second / 3, BetweenHits: 47 * time.Second / 9},
+		stats.Total)
+
+	require.Len(t, stats.Athletes, 3)
+	assert.Equal(t, AthleteStage{
+		CompetitorID: 1,
+		Stage:        1,
+		Shooting:     Shooting{Stages: 2, Hits: 9, Shots: 10, FirstHit: 25 * time.Second, BetweenHits: 27 * time.Second / 7},
+	}, stats.Athletes[0], "the same stage is aggregated across races")
+	assert.Equal(t, 2, stats.Athletes[1].Stage)
+	assert.Equal(t, Shooting{Stages: 1, Shots: 5}, stats.Athletes[1].Shooting, "no hits, no shooting times")
+	assert.Equal(t, 2, stats.Athletes[2].CompetitorID)
+	assert.InDelta(t, 0.6, stats.Athletes[2].Accuracy(), 1e-9)
+
+	require.Len(t, stats.Ranges, 2)
+	assert.Equal(t, 1, stats.Ranges[0].Range)
+	assert.Equal(t, 8, stats.Ranges[0].Hits)
+	assert.Equal(t, 2, stats.Ranges[1].Range)
+	assert.InDelta(t, 0.4, stats.Ranges[1].Accuracy(), 1e-9)
+
+	assert.Equal(t, []TargetStats{
+		{Target: 1, Stages: 4, Misses: 2},
+		{Target: 2, Stages: 4, Misses: 2},
+		{Target: 3, Stages: 4, Misses: 1},
+		{Target: 4, Stages: 4, Misses: 2},
+		{Target: 5, Stages: 4, Misses: 1},
+	}, stats.Targets)
+	assert.InDelta(t, 0.5, stats.Targets[0].MissRate(), 1e-9)
+}
+
+func TestCollectorPositions(t *testing.T) {
+	standing := stage(1, 2, 1, "10:05:00.000", [5]bool{true}, "10:05:30.000")
+	standing.Position = config.PositionStanding
+	prone := stage(1, 1, 1, "10:00:00.000", [5]bool{true, true}, "10:00:20.000", "10:00:22.000")
+	prone.Position = config.PositionProne
+	other := stage(2, 1, 1, "10:00:10.000", [5]bool{true, true, true}, "10:00:40.000", "10:00:42.000", "10:00:44.000")
+	other.Position = config.PositionProne
+
+	c := NewCollector()
+	c.Add([]engine.ShootingStage{standing, prone, other})
+	stats := c.Stats()
+
+	assert.Equal(t, []PositionStats{
+		{Position: config.PositionProne, Shooting: Shooting{Stages: 2, Hits: 5, Shots: 10, FirstHit: 25 * time.Second, BetweenHits: 2 * time.Second}},
+		{Position: config.PositionStanding, Shooting: Shooting{Stages: 1, Hits: 1, Shots: 5, FirstHit: 30 * time.Second}},
+	}, stats.Positions)
+	require.Len(t, stats.AthletePositions, 3)
+	assert.Equal(t, config.PositionProne, stats.AthletePositions[0].Position, "prone comes first")
+	assert.Equal(t, 1, stats.AthletePositions[1].CompetitorID)
+	assert.Equal(t, config.PositionStanding, stats.AthletePositions[1].Position)
+	assert.Equal(t, 2, stats.AthletePositions[2].CompetitorID)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteTable(&buf, stats))
+	assert.Contains(t, buf.String(), "standing  1       1     5      20.0%     00:30.000  00:00.000\n")
+
+	assert.Empty(t, testStats().Positions, "no positions without a configured sequence")
+}
+
+func TestWriteTable(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteTable(&buf, testStats()))
+
+	out := buf.String()
+	assert.Contains(t, out, "races: 2, stages: 4, accuracy: 60.0%\n")
+	assert.Contains(t, out, "1           1      9     10     90.0%     00:25.000  00:03.857\n")
+	assert.Contains(t, out, "1       4       2       50.0%\n")
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteJSON(&buf, testStats()))
+
+	var doc struct {
+		SchemaVersion int `json:"schemaVersion"`
+		Races         int `json:"races"`
+		Athletes      []struct {
+			CompetitorID int     `json:"competitorId"`
+			Stage        int     `json:"stage"`
+			Accuracy     float64 `json:"accuracy"`
+			FirstHit     struct {
+				Ms        int64  `json:"ms"`
+				Formatted string `json:"formatted"`
+			} `json:"firstHit"`
+		} `json:"athletes"`
+		Targets []struct {
+			Target   int     `json:"target"`
+			MissRate float64 `json:"missRate"`
+		} `json:"targets"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+
+	assert.Equal(t, JSONSchemaVersion, doc.SchemaVersion)
+	assert.Equal(t, 2, doc.Races)
+	require.Len(t, doc.Athletes, 3)
+	assert.Equal(t, 1, doc.Athletes[0].CompetitorID)
+	assert.InDelta(t, 0.9, doc.Athletes[0].Accuracy, 1e-9)
+	assert.Equal(t, int64(25000), doc.Athletes[0].FirstHit.Ms)
+	assert.Equal(t, "00:25.000", doc.Athletes[0].FirstHit.Formatted)
+	require.Len(t, doc.Targets, 5)
+	assert.InDelta(t, 0.5, doc.Targets[0].MissRate, 1e-9)
+}
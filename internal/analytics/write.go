@@ -0,0 +1,174 @@
+package analytics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/engine"
+)
+
+// WriteTable prints the statistics as aligned text tables.
+func WriteTable(w io.Writer, stats Stats) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "races: %d, stages: %d, accuracy: %s\n", stats.Races, stats.Total.Stages, formatRate(stats.Total.Accuracy()))
+
+	fmt.Fprintln(tw, "\ncompetitor\tstage\thits\tshots\taccuracy\tfirst hit\tbetween hits")
+	for _, a := range stats.Athletes {
+		fmt.Fprintf(tw, "%d\t%d\t%s\n", a.CompetitorID, a.Stage, formatShooting(a.Shooting))
+	}
+
+	fmt.Fprintln(tw, "\nrange\tstages\thits\tshots\taccuracy\tfirst hit\tbetween hits")
+	for _, r := range stats.Ranges {
+		fmt.Fprintf(tw, "%d\t%d\t%s\n", r.Range, r.Stages, formatShooting(r.Shooting))
+	}
+
+	if len(stats.Positions) > 0 {
+		fmt.Fprintln(tw, "\nposition\tstages\thits\tshots\taccuracy\tfirst hit\tbetween hits")
+		for _, p := range stats.Positions {
+			fmt.Fprintf(tw, "%s\t%d\t%s\n", p.Position, p.Stages, formatShooting(p.Shooting))
+		}
+
+		fmt.Fprintln(tw, "\ncompetitor\tposition\tstages\thits\tshots\taccuracy\tfirst hit\tbetween hits")
+		for _, a := range stats.AthletePositions {
+			fmt.Fprintf(tw, "%d\t%s\t%d\t%s\n", a.CompetitorID, a.Position, a.Stages, formatShooting(a.Shooting))
+		}
+	}
+
+	fmt.Fprintln(tw, "\ntarget\tstages\tmisses\tmiss rate")
+	for _, t := range stats.Targets {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%s\n", t.Target, t.Stages, t.Misses, formatRate(t.MissRate()))
+	}
+	return tw.Flush()
+}
+
+func formatShooting(s Shooting) string {
+	return fmt.Sprintf("%d\t%d\t%s\t%s\t%s",
+		s.Hits, s.Shots, formatRate(s.Accuracy()),
+		engine.FormatDuration(s.FirstHit), engine.FormatDuration(s.BetweenHits))
+}
+
+func formatRate(rate float64) string {
+	return fmt.Sprintf("%.1f%%", rate*100)
+}
+
+// JSONSchemaVersion is bumped on every incompatible change of the JSON statistics.
+const JSONSchemaVersion = 1
+
+type jsonStats struct {
+	SchemaVersion int           `json:"schemaVersion"`
+	Races         int           `json:"races"`
+	Total         jsonShooting  `json:"total"`
+	Athletes      []jsonAthlete `json:"athletes"`
+	Ranges        []jsonRange   `json:"ranges"`
+	Targets       []jsonTarget  `json:"targets"`
+
+	Positions        []jsonPosition        `json:"positions,omitempty"`
+	AthletePositions []jsonAthletePosition `json:"athletePositions,omitempty"`
+}
+
+type jsonDuration struct {
+	Ms        int64  `json:"ms"`
+	Formatted string `json:"formatted"`
+}
+
+type jsonShooting struct {
+	Stages      int          `json:"stages"`
+	Hits        int          `json:"hits"`
+	Shots       int          `json:"shots"`
+	Accuracy    float64      `json:"accuracy"`
+	FirstHit    jsonDuration `json:"firstHit"`
+	BetweenHits jsonDuration `json:"betweenHits"`
+}
+
+type jsonAthlete struct {
+	CompetitorID int `json:"competitorId"`
+	Stage        int `json:"stage"`
+	jsonShooting
+}
+
+type jsonRange struct {
+	Range int `json:"range"`
+	jsonShooting
+}
+
+type jsonPosition struct {
+	Position string `json:"position"`
+	jsonShooting
+}
+
+type jsonAthletePosition struct {
+	CompetitorID int    `json:"competitorId"`
+	Position     string `json:"position"`
+	jsonShooting
+}
+
+type jsonTarget struct {
+	Target   int     `json:"target"`
+	Stages   int     `json:"stages"`
+	Misses   int     `json:"misses"`
+	MissRate float64 `json:"missRate"`
+}
+
+// WriteJSON writes the statistics as a versioned JSON document.
+func WriteJSON(w io.Writer, stats Stats) error {
+	doc := jsonStats{
+		SchemaVersion: JSONSchemaVersion,
+		Races:         stats.Races,
+		Total:         newJSONShooting(stats.Total),
+		Athletes:      make([]jsonAthlete, 0, len(stats.Athletes)),
+		Ranges:        make([]jsonRange, 0, len(stats.Ranges)),
+		Targets:       make([]jsonTarget, 0, len(stats.Targets)),
+	}
+	for _, a := range stats.Athletes {
+		doc.Athletes = append(doc.Athletes, jsonAthlete{
+			CompetitorID: a.CompetitorID,
+			Stage:        a.Stage,
+			jsonShooting: newJSONShooting(a.Shooting),
+		})
+	}
+	for _, r := range stats.Ranges {
+		doc.Ranges = append(doc.Ranges, jsonRange{Range: r.Range, jsonShooting: newJSONShooting(r.Shooting)})
+	}
+	for _, t := range stats.Targets {
+		doc.Targets = append(doc.Targets, jsonTarget{
+			Target:   t.Target,
+			Stages:   t.Stages,
+			Misses:   t.Misses,
+			MissRate: t.MissRate(),
+		})
+	}
+	for _, p := range stats.Positions {
+		doc.Positions = append(doc.Positions, jsonPosition{
+			Position:     string(p.Position),
+			jsonShooting: newJSONShooting(p.Shooting),
+		})
+	}
+	for _, a := range stats.AthletePositions {
+		doc.AthletePositions = append(doc.AthletePositions, jsonAthletePosition{
+			CompetitorID: a.CompetitorID,
+			Position:     string(a.Position),
+			jsonShooting: newJSONShooting(a.Shooting),
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+func newJSONShooting(s Shooting) jsonShooting {
+	return jsonShooting{
+		Stages:      s.Stages,
+		Hits:        s.Hits,
+		Shots:       s.Shots,
+		Accuracy:    s.Accuracy(),
+		FirstHit:    newJSONDuration(s.FirstHit),
+		BetweenHits: newJSONDuration(s.BetweenHits),
+	}
+}
+
+func newJSONDuration(d time.Duration) jsonDuration {
+	return jsonDuration{Ms: d.Milliseconds(), Formatted: engine.FormatDuration(d)}
+}
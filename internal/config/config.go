@@ -7,24 +7,99 @@ import (
 	"time"
 )
 
+// RaceType selects the competition format.
+type RaceType string
+
+const (
+	RaceInterval   RaceType = "interval"   // competitors start one by one every StartDelta
+	RacePursuit    RaceType = "pursuit"    // handicap starts from a previous race result
+	RaceMassStart  RaceType = "mass_start" // everybody starts together at Start
+	RaceIndividual RaceType = "individual" // time penalty per miss instead of penalty laps
+	RaceRelay      RaceType = "relay"      // teams of Legs athletes, each skiing Laps laps
+
+	RaceSingleMixedRelay         RaceType = "single_mixed_relay"         // two athletes alternating on short legs
+	RaceSuperSprintQualification RaceType = "super_sprint_qualification" // interval start, the best Finalists qualify
+	RaceSuperSprintFinal         RaceType = "super_sprint_final"         // mass start of the qualified athletes
+)
+
+// Relay reports whether competitors are teams skiing legs.
+func (t RaceType) Relay() bool {
+	return t == RaceRelay || t == RaceSingleMixedRelay
+}
+
+// CommonStart reports whether every competitor starts at Start.
+func (t RaceType) CommonStart() bool {
+	switch t {
+	case RaceMassStart, RaceRelay, RaceSingleMixedRelay, RaceSuperSprintFinal:
+		return true
+	}
+	return false
+}
+
+// Position is the shooting position of a firing range stage.
+type Position string
+
+const (
+	PositionProne    Position = "prone"
+	PositionStanding Position = "standing"
+)
+
+// parsePosition accepts a position name or its P/S shorthand.
+func parsePosition(str string) (Position, error) {
+	switch str {
+	case "prone", "P":
+		return PositionProne, nil
+	case "standing", "S":
+		return PositionStanding, nil
+	}
+	return "", fmt.Errorf("unknown shooting position %q", str)
+}
+
 type Config struct {
-	Laps        int           // Amount of laps for main distance
-	LapLen      int           // Length of each main lap
-	PenaltyLen  int           // Length of each penalty lap
-	FiringLines int           // Number of firing lines per lap
-	Start       time.Time     // Planned start time for the first competitor
-	StartDelta  time.Duration // Planned interval between starts
+	RaceType           RaceType      // Competition format, interval by default
+	Laps               int           // Amount of laps for main distance
+	Legs               int           // Amount of legs in a relay
+	LegLaps            []int         // Laps of every relay leg, Laps each if empty
+	Finalists          int           // Athletes qualified for a super sprint final
+	LapLen             int           // Length of each main lap
+	PenaltyLen         int           // Length of each penalty lap
+	FiringLines        int           // Number of firing lines per lap
+	Positions          []Position    // Shooting position of every stage of a competitor or relay leg, repeated if shorter
+	Start              time.Time     // Planned start time for the first competitor
+	StartDelta         time.Duration // Planned interval between starts
+	PenaltyMaxSpeed    float64       // Fastest plausible speed on penalty laps, m/s
+	SkippedLoopPenalty time.Duration // Time added for every penalty lap not skied
+	PenaltyTime        time.Duration // Time added for every miss in an individual race
+	PullLapped         bool          // Pull out competitors lapped by the leader
 }
 
-const timeForm = "15:04:05"
+const (
+	timeForm = "15:04:05"
+
+	DefaultPenaltyMaxSpeed    = 8.0
+	DefaultSkippedLoopPenalty = 2 * time.Minute
+	DefaultPenaltyTime        = time.Minute
+	DefaultRelayLegs          = 4
+	DefaultMixedRelayPenalty  = 75
+	DefaultFinalists          = 30
+)
 
 type rawConfig struct {
-	Laps        int    `json:"laps"`
-	LapLen      int    `json:"lapLen"`
-	PenaltyLen  int    `json:"penaltyLen"`
-	FiringLines int    `json:"firingLines"`
-	Start       string `json:"start"`
-	StartDelta  string `json:"startDelta"`
+	RaceType           RaceType `json:"raceType"`
+	Laps               int      `json:"laps"`
+	Legs               int      `json:"legs"`
+	LegLaps            []int    `json:"legLaps"`
+	Finalists          int      `json:"finalists"`
+	LapLen             int      `json:"lapLen"`
+	PenaltyLen         int      `json:"penaltyLen"`
+	FiringLines        int      `json:"firingLines"`
+	Positions          []string `json:"positions"`
+	Start              string   `json:"start"`
+	StartDelta         string   `json:"startDelta"`
+	PenaltyMaxSpeed    float64  `json:"penaltyMaxSpeed"`
+	SkippedLoopPenalty string   `json:"skippedLoopPenalty"`
+	PenaltyTime        string   `json:"penaltyTime"`
+	PullLapped         bool     `json:"pullLapped"`
 }
 
 func (c *Config) UnmarshalJSON(data []byte) error {
@@ -33,10 +108,49 @@ func (c *Config) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
+	switch raw.RaceType {
+	case "":
+		c.RaceType = RaceInterval
+	case RaceInterval, RacePursuit, RaceMassStart, RaceIndividual, RaceRelay,
+		RaceSingleMixedRelay, RaceSuperSprintQualification, RaceSuperSprintFinal:
+		c.RaceType = raw.RaceType
+	default:
+		return fmt.Errorf("unknown raceType %q", raw.RaceType)
+	}
+
 	c.Laps = raw.Laps
 	c.LapLen = raw.LapLen
 	c.PenaltyLen = raw.PenaltyLen
+	if c.RaceType == RaceSingleMixedRelay && c.PenaltyLen == 0 {
+		c.PenaltyLen = DefaultMixedRelayPenalty
+	}
+
+	c.Legs = raw.Legs
+	c.LegLaps = raw.LegLaps
+	if c.Legs == 0 && len(c.LegLaps) > 0 {
+		c.Legs = len(c.LegLaps)
+	}
+	if c.RaceType.Relay() && c.Legs == 0 {
+		c.Legs = DefaultRelayLegs
+	}
+	if len(c.LegLaps) > 0 && len(c.LegLaps) != c.Legs {
+		return fmt.Errorf("legLaps has %d legs, expected %d", len(c.LegLaps), c.Legs)
+	}
+
+	c.Finalists = raw.Finalists
+	if c.Finalists == 0 {
+		c.Finalists = DefaultFinalists
+	}
 	c.FiringLines = raw.FiringLines
+	c.Positions = nil
+	for _, str := range raw.Positions {
+		position, err := parsePosition(str)
+		if err != nil {
+			return err
+		}
+		c.Positions = append(c.Positions, position)
+	}
+	c.PullLapped = raw.PullLapped
 
 	startTime, err := time.Parse(timeForm, raw.Start)
 	if err != nil {
@@ -44,17 +158,54 @@ func (c *Config) UnmarshalJSON(data []byte) error {
 	}
 	c.Start = startTime
 
-	var h, m, s int
-	if _, err := fmt.Sscanf(raw.StartDelta, "%02d:%02d:%02d", &h, &m, &s); err != nil {
+	c.StartDelta, err = parseDuration(raw.StartDelta)
+	if err != nil {
 		return fmt.Errorf("failed to parse startDelta %q: %w", raw.StartDelta, err)
 	}
-	c.StartDelta = time.Duration(h)*time.Hour +
-		time.Duration(m)*time.Minute +
-		time.Duration(s)*time.Second
+
+	c.PenaltyMaxSpeed = raw.PenaltyMaxSpeed
+	if c.PenaltyMaxSpeed == 0 {
+		c.PenaltyMaxSpeed = DefaultPenaltyMaxSpeed
+	}
+
+	c.SkippedLoopPenalty = DefaultSkippedLoopPenalty
+	if raw.SkippedLoopPenalty != "" {
+		c.SkippedLoopPenalty, err = parseDuration(raw.SkippedLoopPenalty)
+		if err != nil {
+			return fmt.Errorf("failed to parse skippedLoopPenalty %q: %w", raw.SkippedLoopPenalty, err)
+		}
+	}
+
+	c.PenaltyTime = DefaultPenaltyTime
+	if raw.PenaltyTime != "" {
+		c.PenaltyTime, err = parseDuration(raw.PenaltyTime)
+		if err != nil {
+			return fmt.Errorf("failed to parse penaltyTime %q: %w", raw.PenaltyTime, err)
+		}
+	}
 
 	return nil
 }
 
+// parseDuration parses a HH:MM:SS duration.
+func parseDuration(str string) (time.Duration, error) {
+	var h, m, s int
+	if _, err := fmt.Sscanf(str, "%02d:%02d:%02d", &h, &m, &s); err != nil {
+		return 0, err
+	}
+	return time.Duration(h)*time.Hour +
+		time.Duration(m)*time.Minute +
+		time.Duration(s)*time.Second, nil
+}
+
+// LapsOfLeg returns the number of laps of the zero-based relay leg.
+func (c Config) LapsOfLeg(leg int) int {
+	if len(c.LegLaps) > 0 {
+		return c.LegLaps[leg]
+	}
+	return c.Laps
+}
+
 func Load(path *string) (Config, error) {
 	data, err := os.ReadFile(*path)
 	if err != nil {
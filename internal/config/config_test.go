@@ -3,6 +3,7 @@ package config
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -82,6 +83,60 @@ func TestUnmarshalJSON(t *testing.T) {
                 "firingLines": 1,
                 "start": "00:00:00",
                 "startDelta": "bad"
+            }`,
+			wantErr: true,
+		},
+		{
+			name: "bad skippedLoopPenalty format",
+			input: `{
+                "laps": 1,
+                "lapLen": 100,
+                "penaltyLen": 50,
+                "firingLines": 1,
+                "start": "00:00:00",
+                "startDelta": "00:00:30",
+                "skippedLoopPenalty": "bad"
+            }`,
+			wantErr: true,
+		},
+		{
+			name: "unknown raceType",
+			input: `{
+                "raceType": "bad",
+                "laps": 1,
+                "lapLen": 100,
+                "penaltyLen": 50,
+                "firingLines": 1,
+                "start": "00:00:00",
+                "startDelta": "00:00:30"
+            }`,
+			wantErr: true,
+		},
+		{
+			name: "legLaps do not match legs",
+			input: `{
+                "raceType": "relay",
+                "legs": 4,
+                "legLaps": [3, 3],
+                "laps": 3,
+                "lapLen": 100,
+                "penaltyLen": 50,
+                "firingLines": 1,
+                "start": "00:00:00",
+                "startDelta": "00:00:30"
+            }`,
+			wantErr: true,
+		},
+		{
+			name: "unknown position",
+			input: `{
+                "laps": 2,
+                "lapLen": 100,
+                "penaltyLen": 50,
+                "firingLines": 1,
+                "positions": ["prone", "kneeling"],
+                "start": "00:00:00",
+                "startDelta": "00:00:30"
             }`,
 			wantErr: true,
 		},
@@ -111,3 +166,63 @@ func TestUnmarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestUnmarshalJSONDefaults(t *testing.T) {
+	var cfg Config
+	err := json.Unmarshal([]byte(`{
+        "laps": 2,
+        "lapLen": 3651,
+        "penaltyLen": 50,
+        "firingLines": 1,
+        "start": "09:30:00",
+        "startDelta": "00:00:30"
+    }`), &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, RaceInterval, cfg.RaceType)
+	assert.Equal(t, DefaultPenaltyMaxSpeed, cfg.PenaltyMaxSpeed)
+	assert.Equal(t, DefaultSkippedLoopPenalty, cfg.SkippedLoopPenalty)
+	assert.Equal(t, DefaultPenaltyTime, cfg.PenaltyTime)
+
+	err = json.Unmarshal([]byte(`{
+        "start": "09:30:00",
+        "startDelta": "00:00:30",
+        "penaltyMaxSpeed": 6.5,
+        "skippedLoopPenalty": "00:01:00"
+    }`), &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 6.5, cfg.PenaltyMaxSpeed)
+	assert.Equal(t, time.Minute, cfg.SkippedLoopPenalty)
+}
+
+func TestUnmarshalJSONSingleMixedRelay(t *testing.T) {
+	var cfg Config
+	err := json.Unmarshal([]byte(`{
+        "raceType": "single_mixed_relay",
+        "legLaps": [3, 3, 3, 4],
+        "lapLen": 1500,
+        "firingLines": 1,
+        "start": "09:30:00",
+        "startDelta": "00:00:00"
+    }`), &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, cfg.Legs)
+	assert.Equal(t, DefaultMixedRelayPenalty, cfg.PenaltyLen)
+	assert.Equal(t, 4, cfg.LapsOfLeg(3))
+	assert.True(t, cfg.RaceType.Relay())
+	assert.True(t, cfg.RaceType.CommonStart())
+}
+
+func TestUnmarshalJSONPositions(t *testing.T) {
+	var cfg Config
+	err := json.Unmarshal([]byte(`{
+        "laps": 4,
+        "lapLen": 2500,
+        "penaltyLen": 150,
+        "firingLines": 1,
+        "positions": ["P", "prone", "S", "standing"],
+        "start": "09:30:00",
+        "startDelta": "00:00:30"
+    }`), &cfg)
+	assert.Nil(t, err)
+	assert.Equal(t, []Position{PositionProne, PositionProne, PositionStanding, PositionStanding}, cfg.Positions)
+}
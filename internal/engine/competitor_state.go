@@ -1,26 +1,101 @@
 package engine
 
 import (
+	"strings"
 	"time"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/config"
 )
 
 type penaltyInterval struct {
 	Start time.Time
 	End   time.Time
+	Visit int // index of the firing range visit the penalty laps follow
+}
+
+const targetsPerRange = 5
+
+// rangeVisit is a single stop of the competitor on a firing range.
+type rangeVisit struct {
+	Range    int
+	Lap      int // zero-based main lap the visit belongs to
+	Lane     int // mass start shooting lane
+	Position config.Position
+	Enter    time.Time
+	Leave    time.Time
+	Targets  [targetsPerRange]bool // knocked down targets, indexed by target number - 1
+	HitTimes []time.Time
+	Spares   int  // relay spare rounds loaded
+	Settled  bool // penalty laps owed for this visit have been checked
+}
+
+func (v *rangeVisit) hits() int {
+	hits := 0
+	for _, down := range v.Targets {
+		if down {
+			hits++
+		}
+	}
+	return hits
+}
+
+// shots returns how many rounds were fired on the visit.
+func (v *rangeVisit) shots() int {
+	return targetsPerRange + v.Spares
+}
+
+// pattern renders the visit as a hit/miss string, e.g. "XX.XX".
+func (v *rangeVisit) pattern() string {
+	var b strings.Builder
+	for _, down := range v.Targets {
+		if down {
+			b.WriteByte('X')
+		} else {
+			b.WriteByte('.')
+		}
+	}
+	return b.String()
 }
 
 type competitorState struct {
 	CompetitorID     int
+	Phase            Phase
 	RegisteredTime   time.Time
 	ScheduledStart   time.Time
 	ActualStart      time.Time
 	NotStarted       bool
+	Lapped           bool
+	Disqualified     bool
 	NotFinished      bool
 	NotFinishedMsg   string
+	OutTime          time.Time // when the competitor was marked NotStarted or NotFinished
 	LapEndTimes      []time.Time
+	Leg              int // zero-based relay leg on the course
 	PenaltyIntervals []penaltyInterval
-	Shots            int
-	Hits             int
-	lineHits         int
+	RangeVisits      []rangeVisit
+	Violations       []string
+	TimePenalty      time.Duration
 	FinishTime       time.Time
 }
+
+// currentVisit returns the last firing range visit. The lifecycle guarantees
+// that one exists while the competitor is on a range.
+func (st *competitorState) currentVisit() *rangeVisit {
+	return &st.RangeVisits[len(st.RangeVisits)-1]
+}
+
+// lapVisits counts firing range visits made during the zero-based main lap.
+func (st *competitorState) lapVisits(lap int) int {
+	visits := 0
+	for _, visit := range st.RangeVisits {
+		if visit.Lap == lap {
+			visits++
+		}
+	}
+	return visits
+}
+
+// racing reports whether the competitor is out on the course.
+func (st *competitorState) racing() bool {
+	return st.Phase == PhaseSkiing || st.Phase == PhaseOnRange || st.Phase == PhaseInPenalty
+}
@@ -3,7 +3,9 @@ package engine
 import (
 	"fmt"
 	"log"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,75 +15,210 @@ import (
 	"github.com/zahartd/biathlon_competitions_system/internal/output"
 )
 
+// Mode selects how the engine reacts to events that break the rules.
+type Mode int
+
+const (
+	ModeLenient Mode = iota // log the violation and keep processing
+	ModeStrict              // fail on the first violation
+)
+
+type Option func(*Engine)
+
+func WithMode(mode Mode) Option {
+	return func(e *Engine) {
+		e.mode = mode
+	}
+}
+
+// WithStartList fixes start times for the race instead of taking them from
+// draws, e.g. handicap starts of a pursuit.
+func WithStartList(starts map[int]time.Time) Option {
+	return func(e *Engine) {
+		e.startList = starts
+	}
+}
+
+// WithRoster joins roster entries into report rows. Competitors registering
+// without an entry are flagged.
+func WithRoster(roster models.Roster) Option {
+	return func(e *Engine) {
+		e.roster = roster
+	}
+}
+
 type Engine struct {
-	cfg          config.Config
-	states       map[int]*competitorState
-	resultLogger *output.Logger
+	cfg       config.Config
+	mode      Mode
+	startList map[int]time.Time
+	roster    models.Roster
+	arrivals  map[int]int // mass start: competitors arrived at each stage
+	states    map[int]*competitorState
+	pending   []*competitorState // drawn competitors with an open start window, in start order
+	lastEvent time.Time          // latest event time seen, the race clock
+	sink      output.EventSink
 }
 
-func NewEngine(cfg config.Config, resultLogger *output.Logger) *Engine {
-	return &Engine{
-		cfg:          cfg,
-		states:       make(map[int]*competitorState),
-		resultLogger: resultLogger,
+func NewEngine(cfg config.Config, sink output.EventSink, opts ...Option) *Engine {
+	e := &Engine{
+		cfg:      cfg,
+		states:   make(map[int]*competitorState),
+		arrivals: make(map[int]int),
+		sink:     sink,
 	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
 }
 
 func (e *Engine) ProcessEvent(event models.Event) error {
+	if e.lastEvent.IsZero() || event.Time.After(e.lastEvent) {
+		e.lastEvent = event.Time
+	}
+	e.closeStartWindows(event.Time)
+
 	state, ok := e.states[event.CompetitorID]
 	if !ok {
 		state = &competitorState{
 			CompetitorID:     event.CompetitorID,
-			LapEndTimes:      make([]time.Time, 0, e.cfg.Laps),
-			PenaltyIntervals: make([]penaltyInterval, 0, e.cfg.Laps*e.cfg.FiringLines),
+			LapEndTimes:      make([]time.Time, 0, e.raceLaps()),
+			PenaltyIntervals: make([]penaltyInterval, 0, e.raceLaps()*e.cfg.FiringLines),
 		}
+		// stored right away, so violations of a rejected first event are kept
 		e.states[event.CompetitorID] = state
 	}
 
-	e.resultLogger.Write(event)
+	if state.NotStarted || state.Lapped {
+		if event.ID == models.EventStart && state.ActualStart.IsZero() {
+			state.ActualStart = event.Time // a late start, kept apart from no start at all
+		}
+		log.Printf("Ignoring eventID=%d for competitor %d out of the race", event.ID, event.CompetitorID)
+		e.writeSkipped(event, output.SkipIgnored, "competitor is out of the race")
+		return nil
+	}
+
+	next, allowed := nextPhase(state.Phase, event.ID)
+	if !allowed {
+		return e.reject(state, event, &TransitionError{
+			CompetitorID: event.CompetitorID,
+			Phase:        state.Phase,
+			EventID:      event.ID,
+		})
+	}
+	if err := e.validate(state, event); err != nil {
+		return e.reject(state, event, err)
+	}
+	state.Phase = next
+
+	e.write(event)
 
 	switch event.ID {
 	case models.EventRegister:
 		state.RegisteredTime = event.Time
-	case models.EventDraw:
-		scheduled, err := time.Parse(events.TimeLayoutHMSMilli, event.ExtraParams[0])
-		if err != nil {
-			return fmt.Errorf("invalid start time for competitor %d: %w",
-				event.CompetitorID, err)
+		if _, ok := e.roster[event.CompetitorID]; e.roster != nil && !ok {
+			if err := e.flag(state, fmt.Errorf("competitor %d is not on the roster", event.CompetitorID)); err != nil {
+				return err
+			}
 		}
+	case models.EventDraw:
+		scheduled, _ := timeParam(event)
 		state.ScheduledStart = scheduled
+		if expected, ok := e.fixedStart(event.CompetitorID); ok {
+			state.ScheduledStart = expected
+			if !scheduled.Equal(expected) {
+				err := fmt.Errorf("competitor %d: drawn start %s differs from start list time %s",
+					event.CompetitorID, event.ExtraParams[0], expected.Format(events.TimeLayoutHMSMilli))
+				if err := e.flag(state, err); err != nil {
+					return err
+				}
+			}
+		}
+		e.addPending(state)
 	case models.EventOnLine:
 		// no op
 	case models.EventStart:
 		state.ActualStart = event.Time
+		if event.Time.Before(state.ScheduledStart) || event.Time.After(e.startWindowClose(state)) {
+			state.NotStarted = true
+			state.OutTime = event.Time
+			state.Phase = PhaseDisqualified
+		}
 	case models.EventFiring:
-		state.lineHits = 0
+		e.settlePenalties(state, event.Time)
+		rangeNum, _ := intParam(event)
+		visit := rangeVisit{
+			Range:    rangeNum,
+			Lap:      len(state.LapEndTimes),
+			Enter:    event.Time,
+			Position: e.stagePosition(state),
+		}
+		if e.cfg.RaceType == config.RaceMassStart {
+			visit.Range = state.lapVisits(visit.Lap) + 1
+			visit.Lane = rangeNum
+			if err := e.checkLane(state, visit.Lane); err != nil {
+				return err
+			}
+		}
+		state.RangeVisits = append(state.RangeVisits, visit)
 	case models.EventHit:
-		state.lineHits++
+		target, _ := intParam(event)
+		visit := state.currentVisit()
+		visit.Targets[target-1] = true
+		visit.HitTimes = append(visit.HitTimes, event.Time)
 	case models.EventLeaveFiring:
-		state.Shots += 5
-		state.Hits += state.lineHits
+		visit := state.currentVisit()
+		visit.Leave = event.Time
+		if e.cfg.RaceType == config.RaceIndividual {
+			misses := targetsPerRange - visit.hits()
+			state.TimePenalty += time.Duration(misses) * e.cfg.PenaltyTime
+		}
 	case models.EventPenaltyEnter:
-		state.PenaltyIntervals = append(state.PenaltyIntervals, penaltyInterval{Start: event.Time})
+		state.PenaltyIntervals = append(state.PenaltyIntervals, penaltyInterval{
+			Start: event.Time,
+			Visit: len(state.RangeVisits) - 1,
+		})
 	case models.EventPenaltyLeave:
-		if len(state.PenaltyIntervals) > 0 {
-			state.PenaltyIntervals[len(state.PenaltyIntervals)-1].End = event.Time
-		} else {
-			return fmt.Errorf("invalid PenaltyLeave event without enter for competitor %d", event.CompetitorID)
-		}
+		state.PenaltyIntervals[len(state.PenaltyIntervals)-1].End = event.Time
 	case models.EventLapEnd:
+		e.settlePenalties(state, event.Time)
+		lap := len(state.LapEndTimes)
 		state.LapEndTimes = append(state.LapEndTimes, event.Time)
-		if len(state.LapEndTimes) == e.cfg.Laps {
+		if e.cfg.PullLapped {
+			e.pullLapped(state, event.Time)
+		}
+		if visits := state.lapVisits(lap); visits != e.cfg.FiringLines {
+			err := fmt.Errorf("competitor %d: lap %d has %d firing range visits, expected %d",
+				event.CompetitorID, lap+1, visits, e.cfg.FiringLines)
+			if err := e.flag(state, err); err != nil {
+				return err
+			}
+		}
+		if len(state.LapEndTimes) == e.raceLaps() {
 			finish := models.Event{
 				Time:         event.Time,
 				ID:           models.EventFinished,
 				CompetitorID: event.CompetitorID,
 			}
 			state.FinishTime = event.Time
-			e.resultLogger.Write(finish)
+			state.Phase = PhaseFinished
+			e.write(finish)
+
+			if stages := e.raceLaps() * e.cfg.FiringLines; len(state.RangeVisits) != stages {
+				err := fmt.Errorf("competitor %d: finished with %d firing range visits, expected %d",
+					event.CompetitorID, len(state.RangeVisits), stages)
+				if err := e.flag(state, err); err != nil {
+					return err
+				}
+			}
 		}
+	case models.EventExchange:
+		state.Leg++
+	case models.EventSpareRound:
+		state.currentVisit().Spares++
 	case models.EventNotContinue:
 		state.NotFinished = true
+		state.OutTime = event.Time
 		state.NotFinishedMsg = strings.Join(event.ExtraParams, " ")
 	default:
 		log.Printf("Unknown eventID=%d for competitor %d", event.ID, event.CompetitorID)
@@ -90,60 +227,395 @@ func (e *Engine) ProcessEvent(event models.Event) error {
 }
 
 func (e *Engine) Finalize() {
-	for cid, st := range e.states {
-		if st.ActualStart.IsZero() && !st.NotFinished {
-			disqualification := models.Event{
-				Time:         st.ScheduledStart,
-				ID:           models.EventDisqualification,
-				CompetitorID: cid,
+	for _, st := range e.sortedStates() {
+		if st.Phase == PhaseUnregistered {
+			continue // only rejected events, nothing to disqualify
+		}
+		if st.NotStarted || st.ActualStart.IsZero() && !st.NotFinished {
+			e.disqualify(st)
+		}
+	}
+}
+
+// validate checks the event parameters against the competitor state before
+// the event is accepted.
+func (e *Engine) validate(state *competitorState, event models.Event) error {
+	switch event.ID {
+	case models.EventPenaltyEnter:
+		if e.cfg.RaceType == config.RaceIndividual {
+			return fmt.Errorf("competitor %d: no penalty laps in an individual race", event.CompetitorID)
+		}
+		if len(state.RangeVisits) == 0 {
+			return fmt.Errorf("competitor %d: penalty laps before any firing range visit", event.CompetitorID)
+		}
+	case models.EventDraw:
+		if _, err := timeParam(event); err != nil {
+			return fmt.Errorf("competitor %d: invalid start time: %w", event.CompetitorID, err)
+		}
+		if _, ok := e.startList[event.CompetitorID]; e.startList != nil && !ok {
+			return fmt.Errorf("competitor %d is not on the start list", event.CompetitorID)
+		}
+	case models.EventFiring:
+		rangeNum, err := intParam(event)
+		if err != nil {
+			return fmt.Errorf("competitor %d: invalid firing range: %w", event.CompetitorID, err)
+		}
+		if e.cfg.RaceType == config.RaceMassStart {
+			if rangeNum < 1 {
+				return fmt.Errorf("competitor %d: invalid firing lane %d", event.CompetitorID, rangeNum)
 			}
-			e.resultLogger.Write(disqualification)
+		} else if rangeNum < 1 || rangeNum > e.cfg.FiringLines {
+			return fmt.Errorf("competitor %d: firing range %d is out of range 1..%d",
+				event.CompetitorID, rangeNum, e.cfg.FiringLines)
+		}
+	case models.EventExchange, models.EventSpareRound, models.EventLapEnd:
+		return e.validateRelay(state, event)
+	case models.EventHit:
+		target, err := intParam(event)
+		if err != nil {
+			return fmt.Errorf("competitor %d: invalid target: %w", event.CompetitorID, err)
+		}
+		if target < 1 || target > targetsPerRange {
+			return fmt.Errorf("competitor %d: target %d is out of range 1..%d",
+				event.CompetitorID, target, targetsPerRange)
+		}
+		if state.currentVisit().Targets[target-1] {
+			return fmt.Errorf("competitor %d: target %d has already been hit on this range",
+				event.CompetitorID, target)
+		}
+	}
+	return nil
+}
+
+// intParam parses the first extra param of the event as an integer.
+func intParam(event models.Event) (int, error) {
+	if len(event.ExtraParams) == 0 {
+		return 0, fmt.Errorf("missing parameter for eventID=%d", event.ID)
+	}
+	return strconv.Atoi(event.ExtraParams[0])
+}
+
+// timeParam parses the first extra param of the event as a clock time.
+func timeParam(event models.Event) (time.Time, error) {
+	if len(event.ExtraParams) == 0 {
+		return time.Time{}, fmt.Errorf("missing parameter for eventID=%d", event.ID)
+	}
+	return time.Parse(events.TimeLayoutHMSMilli, event.ExtraParams[0])
+}
+
+// settlePenalties compares the penalty laps owed after every finished range
+// visit with the laps the competitor could have skied in the recorded penalty
+// intervals. Each missing lap costs SkippedLoopPenalty.
+func (e *Engine) settlePenalties(state *competitorState, now time.Time) {
+	if e.cfg.RaceType == config.RaceIndividual {
+		return
+	}
+	for i := range state.RangeVisits {
+		visit := &state.RangeVisits[i]
+		if visit.Settled || visit.Leave.IsZero() {
+			continue
+		}
+		visit.Settled = true
+
+		owed := targetsPerRange - visit.hits()
+		skied := 0
+		for _, interval := range state.PenaltyIntervals {
+			if interval.Visit == i {
+				skied += e.maxLoops(interval.End.Sub(interval.Start))
+			}
+		}
+		if skied >= owed {
+			continue
+		}
+
+		skipped := owed - skied
+		state.TimePenalty += time.Duration(skipped) * e.cfg.SkippedLoopPenalty
+		e.write(models.Event{
+			Time:         now,
+			ID:           models.EventLoopsSkipped,
+			CompetitorID: state.CompetitorID,
+			ExtraParams:  []string{strconv.Itoa(skipped), strconv.Itoa(owed)},
+		})
+	}
+}
+
+// penaltyStage sums up the penalty loops skied after the visit, the stage-th
+// one of the report row.
+func (e *Engine) penaltyStage(state *competitorState, visit, stage int) PenaltyStage {
+	v := &state.RangeVisits[visit]
+	ps := PenaltyStage{
+		Stage: stage,
+		Range: v.Range,
+		Lap:   v.Lap + 1,
+		Owed:  targetsPerRange - v.hits(),
+	}
+	skied := 0
+	for _, interval := range state.PenaltyIntervals {
+		if interval.Visit == visit && !interval.End.IsZero() {
+			ps.Time += interval.End.Sub(interval.Start)
+			skied += e.maxLoops(interval.End.Sub(interval.Start))
 		}
 	}
+	if v.Settled && skied < ps.Owed {
+		ps.Skipped = ps.Owed - skied
+	}
+	if ps.Time > 0 && ps.Owed > 0 {
+		ps.Speed = float64(e.cfg.PenaltyLen*ps.Owed) / ps.Time.Seconds()
+	}
+	return ps
+}
+
+// maxLoops returns how many penalty laps fit into d at PenaltyMaxSpeed.
+func (e *Engine) maxLoops(d time.Duration) int {
+	if e.cfg.PenaltyLen <= 0 {
+		return 0
+	}
+	return int(d.Seconds() * e.cfg.PenaltyMaxSpeed / float64(e.cfg.PenaltyLen))
+}
+
+// reject reports a violation for an event that is not accepted and records
+// the event as rejected.
+func (e *Engine) reject(state *competitorState, event models.Event, err error) error {
+	e.writeSkipped(event, output.SkipRejected, err.Error())
+	if e.mode == ModeStrict {
+		return err
+	}
+	log.Printf("Skipping event: %s", err)
+	state.Violations = append(state.Violations, err.Error())
+	return nil
+}
+
+// flag reports a violation for an accepted event: strict mode returns it,
+// lenient mode logs it and keeps it for the report.
+func (e *Engine) flag(state *competitorState, err error) error {
+	if e.mode == ModeStrict {
+		return err
+	}
+	log.Printf("Violation: %s", err)
+	state.Violations = append(state.Violations, err.Error())
+	return nil
+}
+
+// write passes the event to the sink. A failing sink doesn't stop timing, so
+// its error is only logged.
+func (e *Engine) write(event models.Event) {
+	if err := e.sink.Write(event); err != nil {
+		log.Printf("Failed to write event %d of competitor %d: %s", event.ID, event.CompetitorID, err)
+	}
+}
+
+// writeSkipped passes an event the engine did not accept to the sink, if it
+// records such events.
+func (e *Engine) writeSkipped(event models.Event, status output.SkipStatus, reason string) {
+	sink, ok := e.sink.(output.SkipSink)
+	if !ok {
+		return
+	}
+	if err := sink.WriteSkipped(event, status, reason); err != nil {
+		log.Printf("Failed to write skipped event %d of competitor %d: %s", event.ID, event.CompetitorID, err)
+	}
+}
+
+// fixedStart returns the start time the competitor must have regardless of
+// the draw, if the race defines one.
+func (e *Engine) fixedStart(competitorID int) (time.Time, bool) {
+	if e.cfg.RaceType.CommonStart() {
+		return e.cfg.Start, true
+	}
+	start, ok := e.startList[competitorID]
+	return start, ok
+}
+
+// raceStart returns the moment the competitor's race time is counted from.
+// Pursuit and common start results are finish line order, so the clock runs
+// from the first start.
+func (e *Engine) raceStart(state *competitorState) time.Time {
+	if e.cfg.RaceType == config.RacePursuit || e.cfg.RaceType.CommonStart() {
+		return e.cfg.Start
+	}
+	return state.ActualStart
+}
+
+// startWindowClose returns the last moment the competitor is allowed to start.
+func (e *Engine) startWindowClose(st *competitorState) time.Time {
+	return st.ScheduledStart.Add(e.cfg.StartDelta)
+}
+
+// closeStartWindows disqualifies every drawn competitor whose start window
+// has closed by now without a valid start. Windows close in start order, so
+// only the head of the pending queue needs a look.
+func (e *Engine) closeStartWindows(now time.Time) {
+	for len(e.pending) > 0 {
+		st := e.pending[0]
+		if !now.After(e.startWindowClose(st)) {
+			return
+		}
+		e.pending = e.pending[1:]
+		if st.NotStarted || st.ActualStart.IsZero() && !st.NotFinished {
+			e.disqualify(st)
+		}
+	}
+}
+
+// addPending queues the drawn competitor until its start window closes.
+func (e *Engine) addPending(st *competitorState) {
+	i := sort.Search(len(e.pending), func(i int) bool { return startsBefore(st, e.pending[i]) })
+	e.pending = slices.Insert(e.pending, i, st)
+}
+
+func (e *Engine) disqualify(st *competitorState) {
+	if st.Disqualified {
+		return
+	}
+	st.NotStarted = true
+	st.Disqualified = true
+	st.Phase = PhaseDisqualified
+	disqualification := models.Event{
+		Time:         e.startWindowClose(st),
+		ID:           models.EventDisqualification,
+		CompetitorID: st.CompetitorID,
+	}
+	if st.OutTime.IsZero() {
+		st.OutTime = disqualification.Time
+	}
+	e.write(disqualification)
+}
+
+// sortedStates returns competitors ordered by scheduled start, then by ID.
+func (e *Engine) sortedStates() []*competitorState {
+	states := make([]*competitorState, 0, len(e.states))
+	for _, st := range e.states {
+		states = append(states, st)
+	}
+	sort.Slice(states, func(i, j int) bool { return startsBefore(states[i], states[j]) })
+	return states
+}
+
+// startsBefore orders competitors by scheduled start, then by ID.
+func startsBefore(a, b *competitorState) bool {
+	if !a.ScheduledStart.Equal(b.ScheduledStart) {
+		return a.ScheduledStart.Before(b.ScheduledStart)
+	}
+	return a.CompetitorID < b.CompetitorID
 }
 
+// GetReport returns report rows in start list order with result places filled in.
 func (e *Engine) GetReport() []ReportRow {
 	var rows []ReportRow
-	for _, state := range e.states {
+	for _, state := range e.sortedStates() {
 		row := ReportRow{
 			CompetitorID:   state.CompetitorID,
-			Hits:           state.Hits,
-			Shots:          state.Shots,
 			ScheduledStart: state.ScheduledStart,
+			ActualStart:    state.ActualStart,
+			Violations:     state.Violations,
+			TimePenalty:    state.TimePenalty,
+			Disqualified:   state.Disqualified && !state.ActualStart.IsZero(),
+			NotFinishedMsg: state.NotFinishedMsg,
+			Entry:          e.roster[state.CompetitorID],
 		}
+		e.fillSplits(&row, state, 0, len(state.LapEndTimes)+1)
 
 		switch {
 		case state.NotFinished:
-			row.Status = "NotFinished"
-		case state.NotStarted:
-			row.Status = "NotStarted"
+			row.Status = StatusNotFinished
+		case state.NotStarted, state.Phase == PhaseUnregistered:
+			row.Status = StatusNotStarted
 		default:
-			row.Status = "Finished"
+			row.Status = StatusFinished
 		}
-
-		prev := state.ScheduledStart
-		for _, end := range state.LapEndTimes {
-			lapTime := end.Sub(prev)
-			row.LapTimes = append(row.LapTimes, lapTime)
-			row.LapSpeeds = append(row.LapSpeeds, float64(e.cfg.LapLen)/lapTime.Seconds()) // metr / sec
-			prev = end
+		if row.Status == StatusFinished && !state.FinishTime.IsZero() {
+			row.TotalTime = state.FinishTime.Sub(e.raceStart(state)) + state.TimePenalty
 		}
-
-		var totalPen time.Duration
-		for _, interval := range state.PenaltyIntervals {
-			totalPen += interval.End.Sub(interval.Start)
+		if e.cfg.RaceType == config.RaceIndividual {
+			row.PenaltyTime = state.TimePenalty
+			row.PenaltySpeed = 0
+			row.TimedPenalty = true
 		}
-		row.PenaltyTime = totalPen
-		penCount := row.Shots - row.Hits
-		if totalPen > 0 && penCount > 0 {
-			row.PenaltySpeed = float64(e.cfg.PenaltyLen*penCount) / totalPen.Seconds()
+		if e.cfg.RaceType.Relay() {
+			row.Legs = e.legRows(state, row.Status)
 		}
 
 		rows = append(rows, row)
 	}
 
-	sort.Slice(rows, func(i, j int) bool {
-		return rows[i].ScheduledStart.Before(rows[j].ScheduledStart)
-	})
+	rankRows(rows)
+	if e.cfg.RaceType == config.RaceSuperSprintQualification {
+		markQualified(rows, e.cfg.Finalists)
+	}
 	return rows
 }
+
+// fillSplits fills lap, shooting and penalty lap figures of the row from the
+// competitor's zero-based main laps in [from, to).
+func (e *Engine) fillSplits(row *ReportRow, state *competitorState, from, to int) {
+	prev := state.ScheduledStart
+	if from > 0 {
+		prev = state.LapEndTimes[from-1]
+	}
+	for _, end := range state.LapEndTimes[from:min(to, len(state.LapEndTimes))] {
+		lapTime := end.Sub(prev)
+		row.LapTimes = append(row.LapTimes, lapTime)
+		row.LapSpeeds = append(row.LapSpeeds, float64(e.cfg.LapLen)/lapTime.Seconds()) // metr / sec
+		prev = end
+	}
+	row.LapRangeTimes = make([]time.Duration, len(row.LapTimes))
+	row.LapPenaltyTimes = make([]time.Duration, len(row.LapTimes))
+
+	loopsOwed := 0
+	for i := range state.RangeVisits {
+		visit := &state.RangeVisits[i]
+		if visit.Lap < from || visit.Lap >= to {
+			continue
+		}
+		row.Shooting = append(row.Shooting, visit.pattern())
+		row.StageLaps = append(row.StageLaps, visit.Lap-from+1)
+		if visit.Position != "" {
+			row.StagePositions = append(row.StagePositions, visit.Position)
+			row.addPositionShooting(visit)
+		}
+		if visit.Leave.IsZero() {
+			row.StageRangeTimes = append(row.StageRangeTimes, 0)
+			continue
+		}
+		rangeTime := visit.Leave.Sub(visit.Enter)
+		row.StageRangeTimes = append(row.StageRangeTimes, rangeTime)
+		if e.cfg.RaceType != config.RaceIndividual {
+			if stage := e.penaltyStage(state, i, len(row.Shooting)); stage.Owed > 0 || stage.Time > 0 {
+				row.PenaltyStages = append(row.PenaltyStages, stage)
+			}
+		}
+		if lap := visit.Lap - from; lap < len(row.LapTimes) {
+			row.LapRangeTimes[lap] += rangeTime
+		}
+		row.Hits += visit.hits()
+		row.Shots += visit.shots()
+		loopsOwed += targetsPerRange - visit.hits()
+	}
+
+	var totalPen time.Duration
+	for _, interval := range state.PenaltyIntervals {
+		if interval.Visit < 0 {
+			continue // penalty loops before any firing range visit follow no stage
+		}
+		if interval.End.IsZero() {
+			continue // never left the penalty loops, nothing to time
+		}
+		if lap := state.RangeVisits[interval.Visit].Lap; lap >= from && lap < to {
+			totalPen += interval.End.Sub(interval.Start)
+			if lap-from < len(row.LapTimes) {
+				row.LapPenaltyTimes[lap-from] += interval.End.Sub(interval.Start)
+			}
+		}
+	}
+	row.PenaltyTime = totalPen
+
+	// what is left of a lap after the range and the penalty loops is skiing
+	for i, lapTime := range row.LapTimes {
+		course := lapTime - row.LapRangeTimes[i] - row.LapPenaltyTimes[i]
+		row.LapCourseTimes = append(row.LapCourseTimes, course)
+		row.LapSkiSpeeds = append(row.LapSkiSpeeds, float64(e.cfg.LapLen)/course.Seconds())
+	}
+	if totalPen > 0 && loopsOwed > 0 {
+		row.PenaltySpeed = float64(e.cfg.PenaltyLen*loopsOwed) / totalPen.Seconds()
+	}
+}
@@ -0,0 +1,1178 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/config"
+	"github.com/zahartd/biathlon_competitions_system/internal/events"
+	"github.com/zahartd/biathlon_competitions_system/internal/models"
+	"github.com/zahartd/biathlon_competitions_system/internal/output"
+)
+
+func testConfig() config.Config {
+	return config.Config{
+		Laps:        1,
+		LapLen:      1000,
+		PenaltyLen:  100,
+		FiringLines: 1,
+		Start:       time.Date(0, time.January, 1, 10, 0, 0, 0, time.UTC),
+		StartDelta:  30 * time.Second,
+	}
+}
+
+// runEvents feeds raw event lines into a fresh engine and returns it together
+// with the produced output log.
+func runEvents(t *testing.T, cfg config.Config, lines ...string) (*Engine, string) {
+	t.Helper()
+	var log bytes.Buffer
+	e := NewEngine(cfg, output.NewLogger(&log))
+	for _, event := range parseEvents(t, lines...) {
+		require.NoError(t, e.ProcessEvent(event))
+	}
+	e.Finalize()
+	return e, log.String()
+}
+
+func parseEvents(t *testing.T, lines ...string) []models.Event {
+	t.Helper()
+	parser := events.NewParser()
+	parsed := make([]models.Event, 0, len(lines))
+	for _, line := range lines {
+		event, err := parser.ParseEvent(line)
+		require.NoError(t, err)
+		parsed = append(parsed, event)
+	}
+	return parsed
+}
+
+func TestStartWindow(t *testing.T) {
+	tests := []struct {
+		name             string
+		lines            []string
+		wantStatus       string
+		wantDisqualified bool
+		wantLog          string
+	}{
+		{
+			name: "start inside window",
+			lines: []string{
+				"[09:00:00.000] 1 1",
+				"[09:10:00.000] 2 1 10:00:00.000",
+				"[09:59:00.000] 3 1",
+				"[10:00:29.000] 4 1",
+				"[10:05:00.000] 10 1",
+			},
+			wantStatus: "Finished",
+		},
+		{
+			name: "late start",
+			lines: []string{
+				"[09:00:00.000] 1 1",
+				"[09:10:00.000] 2 1 10:00:00.000",
+				"[09:59:00.000] 3 1",
+				"[10:00:31.000] 4 1",
+				"[10:05:00.000] 10 1",
+			},
+			wantStatus:       "NotStarted",
+			wantDisqualified: true,
+			wantLog:          "[10:00:30.000] The competitor(1) is disqualified\n",
+		},
+		{
+			name: "false start",
+			lines: []string{
+				"[09:00:00.000] 1 1",
+				"[09:10:00.000] 2 1 10:00:00.000",
+				"[09:59:00.000] 3 1",
+				"[09:59:59.000] 4 1",
+				"[10:05:00.000] 10 1",
+			},
+			wantStatus:       "NotStarted",
+			wantDisqualified: true,
+			wantLog: "[09:59:59.000] The competitor(1) has started\n" +
+				"[10:00:30.000] The competitor(1) is disqualified\n",
+		},
+		{
+			name: "no start at all",
+			lines: []string{
+				"[09:00:00.000] 1 1",
+				"[09:10:00.000] 2 1 10:00:00.000",
+			},
+			wantStatus: "NotStarted",
+			wantLog:    "[10:00:30.000] The competitor(1) is disqualified\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e, log := runEvents(t, testConfig(), tc.lines...)
+			rows := e.GetReport()
+			require.Len(t, rows, 1)
+			assert.Equal(t, tc.wantStatus, rows[0].Status)
+			assert.Equal(t, tc.wantDisqualified, rows[0].Disqualified, "only a start outside the window disqualifies")
+			assert.True(t, strings.HasSuffix(log, tc.wantLog), "unexpected log tail:\n%s", log)
+			if tc.wantStatus == "NotStarted" {
+				assert.NotContains(t, log, "ended the main lap")
+			}
+		})
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	prefix := []string{
+		"[09:00:00.000] 1 1",
+		"[09:10:00.000] 2 1 10:00:00.000",
+		"[09:59:00.000] 3 1",
+		"[10:00:00.000] 4 1",
+	}
+	tests := []struct {
+		name      string
+		lines     []string
+		wantPhase Phase
+		wantEvent models.EventID
+	}{
+		{
+			name:      "second registration",
+			lines:     []string{"[09:00:00.000] 1 1", "[09:01:00.000] 1 1"},
+			wantPhase: PhaseRegistered,
+			wantEvent: models.EventRegister,
+		},
+		{
+			name:      "event before registration",
+			lines:     []string{"[09:00:00.000] 3 1"},
+			wantPhase: PhaseUnregistered,
+			wantEvent: models.EventOnLine,
+		},
+		{
+			name:      "hit before firing",
+			lines:     append(prefix, "[10:01:00.000] 6 1 1"),
+			wantPhase: PhaseSkiing,
+			wantEvent: models.EventHit,
+		},
+		{
+			name:      "lap end on range",
+			lines:     append(prefix, "[10:01:00.000] 5 1 1", "[10:02:00.000] 10 1"),
+			wantPhase: PhaseOnRange,
+			wantEvent: models.EventLapEnd,
+		},
+		{
+			name: "event after finish",
+			lines: append(prefix,
+				"[10:01:00.000] 5 1 1",
+				"[10:02:00.000] 7 1",
+				"[10:05:00.000] 10 1",
+				"[10:06:00.000] 8 1",
+			),
+			wantPhase: PhaseFinished,
+			wantEvent: models.EventPenaltyEnter,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var strictLog, lenientLog bytes.Buffer
+			strict := NewEngine(testConfig(), output.NewLogger(&strictLog), WithMode(ModeStrict))
+			lenient := NewEngine(testConfig(), output.NewLogger(&lenientLog))
+
+			var err error
+			for _, event := range parseEvents(t, tc.lines...) {
+				assert.NoError(t, lenient.ProcessEvent(event))
+				if err == nil {
+					err = strict.ProcessEvent(event)
+				}
+			}
+
+			var transitionErr *TransitionError
+			require.ErrorAs(t, err, &transitionErr)
+			assert.Equal(t, tc.wantPhase, transitionErr.Phase)
+			assert.Equal(t, tc.wantEvent, transitionErr.EventID)
+			assert.Equal(t, strictLog.String(), lenientLog.String(), "rejected events must not be logged")
+		})
+	}
+}
+
+func TestShooting(t *testing.T) {
+	cfg := testConfig()
+	lines := []string{
+		"[09:00:00.000] 1 1",
+		"[09:10:00.000] 2 1 10:00:00.000",
+		"[09:59:00.000] 3 1",
+		"[10:00:00.000] 4 1",
+		"[10:01:00.000] 5 1 1",
+		"[10:01:01.000] 6 1 1",
+		"[10:01:02.000] 6 1 2",
+		"[10:01:03.000] 6 1 2",
+		"[10:01:04.000] 6 1 9",
+		"[10:01:05.000] 6 1 5",
+		"[10:01:06.000] 7 1",
+	}
+
+	e, log := runEvents(t, cfg, lines...)
+	rows := e.GetReport()
+	require.Len(t, rows, 1)
+	assert.Equal(t, []string{"XX..X"}, rows[0].Shooting)
+	assert.Equal(t, 3, rows[0].Hits)
+	assert.Equal(t, 5, rows[0].Shots)
+	assert.NotContains(t, log, "target(9)")
+
+	strict := NewEngine(cfg, output.NewLogger(&bytes.Buffer{}), WithMode(ModeStrict))
+	var err error
+	for _, event := range parseEvents(t, lines...) {
+		if err = strict.ProcessEvent(event); err != nil {
+			break
+		}
+	}
+	assert.ErrorContains(t, err, "target 2 has already been hit")
+}
+
+func TestFiringRangeVisits(t *testing.T) {
+	cfg := testConfig()
+	cfg.Laps = 2
+	cfg.FiringLines = 2
+	prefix := []string{
+		"[09:00:00.000] 1 1",
+		"[09:10:00.000] 2 1 10:00:00.000",
+		"[09:59:00.000] 3 1",
+		"[10:00:00.000] 4 1",
+	}
+	tests := []struct {
+		name           string
+		lines          []string
+		wantViolations []string
+	}{
+		{
+			name: "all stages",
+			lines: append(prefix,
+				"[10:01:00.000] 5 1 1", "[10:01:30.000] 7 1",
+				"[10:02:00.000] 5 1 2", "[10:02:30.000] 7 1",
+				"[10:03:00.000] 10 1",
+				"[10:04:00.000] 5 1 1", "[10:04:30.000] 7 1",
+				"[10:05:00.000] 5 1 2", "[10:05:30.000] 7 1",
+				"[10:06:00.000] 10 1",
+			),
+		},
+		{
+			name: "range number out of config",
+			lines: append(prefix,
+				"[10:01:00.000] 5 1 3",
+			),
+			wantViolations: []string{"competitor 1: firing range 3 is out of range 1..2"},
+		},
+		{
+			name: "missing stage",
+			lines: append(prefix,
+				"[10:01:00.000] 5 1 1", "[10:01:30.000] 7 1",
+				"[10:03:00.000] 10 1",
+				"[10:04:00.000] 5 1 1", "[10:04:30.000] 7 1",
+				"[10:05:00.000] 5 1 2", "[10:05:30.000] 7 1",
+				"[10:06:00.000] 10 1",
+			),
+			wantViolations: []string{
+				"competitor 1: lap 1 has 1 firing range visits, expected 2",
+				"competitor 1: finished with 3 firing range visits, expected 4",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e, _ := runEvents(t, cfg, tc.lines...)
+			rows := e.GetReport()
+			require.Len(t, rows, 1)
+			assert.Equal(t, tc.wantViolations, rows[0].Violations)
+		})
+	}
+}
+
+func TestSkippedPenaltyLoops(t *testing.T) {
+	cfg := testConfig()
+	cfg.PenaltyMaxSpeed = 10
+	cfg.SkippedLoopPenalty = 2 * time.Minute
+	prefix := []string{
+		"[09:00:00.000] 1 1",
+		"[09:10:00.000] 2 1 10:00:00.000",
+		"[09:59:00.000] 3 1",
+		"[10:00:00.000] 4 1",
+		"[10:01:00.000] 5 1 1",
+		"[10:01:01.000] 6 1 1",
+		"[10:01:02.000] 6 1 2",
+		"[10:01:03.000] 6 1 3",
+		"[10:01:04.000] 7 1",
+	}
+	tests := []struct {
+		name        string
+		lines       []string
+		wantPenalty time.Duration
+		wantLog     string
+	}{
+		{
+			name: "all loops skied",
+			lines: append(prefix,
+				"[10:01:10.000] 8 1",
+				"[10:01:30.000] 9 1",
+				"[10:05:00.000] 10 1",
+			),
+		},
+		{
+			name: "one loop short",
+			lines: append(prefix,
+				"[10:01:10.000] 8 1",
+				"[10:01:25.000] 9 1",
+				"[10:05:00.000] 10 1",
+			),
+			wantPenalty: 2 * time.Minute,
+			wantLog:     "[10:05:00.000] The competitor(1) skipped 1 of 2 penalty laps\n",
+		},
+		{
+			name:        "penalty laps ignored",
+			lines:       append(prefix, "[10:05:00.000] 10 1"),
+			wantPenalty: 4 * time.Minute,
+			wantLog:     "[10:05:00.000] The competitor(1) skipped 2 of 2 penalty laps\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			e, log := runEvents(t, cfg, tc.lines...)
+			rows := e.GetReport()
+			require.Len(t, rows, 1)
+			assert.Equal(t, tc.wantPenalty, rows[0].TimePenalty)
+			if tc.wantLog == "" {
+				assert.NotContains(t, log, "skipped")
+			} else {
+				assert.Contains(t, log, tc.wantLog)
+			}
+		})
+	}
+}
+
+func TestPursuit(t *testing.T) {
+	cfg := testConfig()
+	cfg.RaceType = config.RacePursuit
+	cfg.Laps = 3
+	cfg.FiringLines = 0
+	cfg.PullLapped = true
+	start := cfg.Start
+	starts := map[int]time.Time{
+		1: start,
+		2: start.Add(20 * time.Second),
+		3: start.Add(time.Minute),
+	}
+
+	var log bytes.Buffer
+	e := NewEngine(cfg, output.NewLogger(&log), WithStartList(starts))
+	lines := []string{
+		"[09:00:00.000] 1 1",
+		"[09:00:00.000] 1 2",
+		"[09:00:00.000] 1 3",
+		"[09:00:00.000] 1 4",
+		"[09:10:00.000] 2 1 10:00:00.000",
+		"[09:10:00.000] 2 2 10:00:30.000",
+		"[09:10:00.000] 2 3 10:01:00.000",
+		"[09:10:00.000] 2 4 10:02:00.000",
+		"[09:59:00.000] 3 1",
+		"[09:59:00.000] 3 2",
+		"[09:59:00.000] 3 3",
+		"[10:00:00.000] 4 1",
+		"[10:00:20.000] 4 2",
+		"[10:01:00.000] 4 3",
+		"[10:05:00.000] 10 1",
+		"[10:06:00.000] 10 2",
+		"[10:10:00.000] 10 1",
+		"[10:11:00.000] 10 3",
+		"[10:12:00.000] 10 2",
+		"[10:15:00.000] 10 1",
+		"[10:17:00.000] 10 2",
+	}
+	for _, event := range parseEvents(t, lines...) {
+		require.NoError(t, e.ProcessEvent(event))
+	}
+	e.Finalize()
+
+	rows := e.GetReport()
+	SortByResult(rows)
+	require.Len(t, rows, 4)
+
+	assert.Equal(t, 1, rows[0].CompetitorID)
+	assert.Equal(t, 1, rows[0].Rank)
+	assert.Equal(t, 15*time.Minute, rows[0].TotalTime)
+
+	assert.Equal(t, 2, rows[1].CompetitorID)
+	assert.Equal(t, 2, rows[1].Rank)
+	assert.Equal(t, 2*time.Minute, rows[1].Behind)
+	assert.Equal(t, start.Add(20*time.Second), rows[1].ScheduledStart)
+	assert.Len(t, rows[1].Violations, 1)
+
+	assert.Equal(t, 3, rows[2].CompetitorID)
+	assert.Equal(t, StatusNotFinished, rows[2].Status)
+	assert.Contains(t, log.String(),
+		"[10:10:00.000] The competitor(3) was lapped by the leader on lap 2 and pulled out")
+
+	assert.Equal(t, 4, rows[3].CompetitorID)
+	assert.Equal(t, StatusNotStarted, rows[3].Status)
+	assert.Equal(t, []string{"competitor 4 is not on the start list"}, rows[3].Violations)
+}
+
+func TestMassStart(t *testing.T) {
+	cfg := testConfig()
+	cfg.RaceType = config.RaceMassStart
+	cfg.Laps = 2
+
+	lines := []string{
+		"[09:00:00.000] 1 9",
+		"[09:00:00.000] 1 5",
+		"[09:00:00.000] 1 7",
+		"[09:10:00.000] 2 9 10:00:00.000",
+		"[09:10:00.000] 2 5 10:00:00.000",
+		"[09:10:00.000] 2 7 10:01:00.000",
+		"[09:59:00.000] 3 9",
+		"[09:59:00.000] 3 5",
+		"[09:59:00.000] 3 7",
+		"[10:00:00.000] 4 9",
+		"[10:00:00.000] 4 5",
+		"[10:00:00.000] 4 7",
+		// first stage: lanes by bib
+		"[10:02:00.000] 5 9 3",
+		"[10:02:01.000] 5 5 1",
+		"[10:02:02.000] 5 7 3",
+		"[10:02:30.000] 7 9",
+		"[10:02:31.000] 7 5",
+		"[10:02:32.000] 7 7",
+		"[10:05:00.000] 10 5",
+		"[10:05:01.000] 10 9",
+		"[10:05:02.000] 10 7",
+		// second stage: lanes by arrival
+		"[10:07:00.000] 5 7 1",
+		"[10:07:01.000] 5 5 2",
+		"[10:07:02.000] 5 9 3",
+		"[10:07:30.000] 7 7",
+		"[10:07:31.000] 7 5",
+		"[10:07:32.000] 7 9",
+		"[10:10:00.000] 10 7",
+		"[10:10:01.000] 10 9",
+		"[10:10:02.000] 10 5",
+	}
+	e, _ := runEvents(t, cfg, lines...)
+
+	rows := e.GetReport()
+	SortByResult(rows)
+	require.Len(t, rows, 3)
+
+	ids := []int{rows[0].CompetitorID, rows[1].CompetitorID, rows[2].CompetitorID}
+	assert.Equal(t, []int{7, 9, 5}, ids, "result must follow finish line order")
+	assert.Equal(t, 10*time.Minute, rows[0].TotalTime)
+	assert.Equal(t, 5*time.Minute+2*time.Second, rows[0].LapTimes[0], "laps are measured from the common start")
+	assert.Equal(t, []string{
+		"competitor 7: drawn start 10:01:00.000 differs from start list time 10:00:00.000",
+		"competitor 7: stage 1 shot from lane 3, expected lane 2",
+	}, rows[0].Violations)
+	assert.Empty(t, rows[1].Violations)
+	assert.Empty(t, rows[2].Violations)
+}
+
+func TestIndividual(t *testing.T) {
+	cfg := testConfig()
+	cfg.RaceType = config.RaceIndividual
+	cfg.PenaltyTime = time.Minute
+
+	e, log := runEvents(t, cfg,
+		"[09:00:00.000] 1 1",
+		"[09:10:00.000] 2 1 10:00:00.000",
+		"[09:59:00.000] 3 1",
+		"[10:00:00.000] 4 1",
+		"[10:01:00.000] 5 1 1",
+		"[10:01:01.000] 6 1 1",
+		"[10:01:02.000] 6 1 2",
+		"[10:01:03.000] 6 1 3",
+		"[10:01:04.000] 7 1",
+		"[10:01:10.000] 8 1",
+		"[10:05:00.000] 10 1",
+	)
+
+	rows := e.GetReport()
+	require.Len(t, rows, 1)
+	assert.Equal(t, 2*time.Minute, rows[0].PenaltyTime)
+	assert.Equal(t, 7*time.Minute, rows[0].TotalTime)
+	assert.Equal(t, "[Finished] 1 [{05:00.000, 3.333}] {02:00.000, } 3/5\n", rows[0].Format())
+	assert.Equal(t, []string{"competitor 1: no penalty laps in an individual race"}, rows[0].Violations)
+	assert.NotContains(t, log, "penalty laps")
+}
+
+func TestRelay(t *testing.T) {
+	cfg := testConfig()
+	cfg.RaceType = config.RaceRelay
+	cfg.Legs = 2
+	cfg.PenaltyMaxSpeed = 10
+
+	e, log := runEvents(t, cfg,
+		"[09:00:00.000] 1 1",
+		"[09:10:00.000] 2 1 10:00:00.000",
+		"[09:59:00.000] 3 1",
+		"[10:00:00.000] 4 1",
+		"[10:01:00.000] 5 1 1",
+		"[10:01:01.000] 6 1 1",
+		"[10:01:02.000] 6 1 2",
+		"[10:01:03.000] 6 1 3",
+		"[10:01:04.000] 6 1 4",
+		"[10:01:05.000] 13 1",
+		"[10:01:06.000] 6 1 5",
+		"[10:01:10.000] 7 1",
+		"[10:05:00.000] 10 1",
+		"[10:05:00.000] 10 1",
+		"[10:05:00.000] 12 1",
+		"[10:06:00.000] 5 1 1",
+		"[10:06:01.000] 6 1 1",
+		"[10:06:02.000] 13 1",
+		"[10:06:03.000] 13 1",
+		"[10:06:04.000] 6 1 2",
+		"[10:06:05.000] 13 1",
+		"[10:06:06.000] 13 1",
+		"[10:06:07.000] 6 1 3",
+		"[10:06:08.000] 7 1",
+		"[10:06:10.000] 8 1",
+		"[10:06:30.000] 9 1",
+		"[10:10:00.000] 10 1",
+		"[10:10:00.000] 12 1",
+	)
+
+	rows := e.GetReport()
+	require.Len(t, rows, 1)
+	team := rows[0]
+	assert.Equal(t, StatusFinished, team.Status)
+	assert.Equal(t, 10*time.Minute, team.TotalTime)
+	assert.Equal(t, 8, team.Hits)
+	assert.Equal(t, 14, team.Shots)
+	assert.Equal(t, []string{
+		"competitor 1: leg 1 must hand over before the next lap",
+		"competitor 1: all 3 spare rounds are already loaded",
+		"competitor 1: eventID=12 is not allowed while finished",
+	}, team.Violations)
+	assert.NotContains(t, log, "skipped")
+	assert.Equal(t,
+		"[Finished] 1 10:00.000 {00:20.000, 10.000} 8/14\n"+
+			"    leg 1: [{05:00.000, 3.333}] {00:00.000, 0.000} 5/6\n"+
+			"    leg 2: [{05:00.000, 3.333}] {00:20.000, 10.000} 3/8\n",
+		team.Format())
+}
+
+func TestSingleMixedRelay(t *testing.T) {
+	cfg := testConfig()
+	cfg.RaceType = config.RaceSingleMixedRelay
+	cfg.Legs = 2
+	cfg.LegLaps = []int{1, 2}
+	cfg.FiringLines = 0
+
+	e, _ := runEvents(t, cfg,
+		"[09:00:00.000] 1 1",
+		"[09:10:00.000] 2 1 10:00:00.000",
+		"[09:59:00.000] 3 1",
+		"[10:00:00.000] 4 1",
+		"[10:03:00.000] 10 1",
+		"[10:03:00.000] 12 1",
+		"[10:06:00.000] 10 1",
+		"[10:09:00.000] 10 1",
+	)
+
+	rows := e.GetReport()
+	require.Len(t, rows, 1)
+	assert.Empty(t, rows[0].Violations)
+	assert.Equal(t, 9*time.Minute, rows[0].TotalTime)
+	assert.Equal(t,
+		"[Finished] 1 09:00.000 {00:00.000, 0.000} 0/0\n"+
+			"    leg 1 (athlete 1): [{03:00.000, 5.556}] {00:00.000, 0.000} 0/0\n"+
+			"    leg 2 (athlete 2): [{03:00.000, 5.556}, {03:00.000, 5.556}] {00:00.000, 0.000} 0/0\n",
+		rows[0].Format())
+}
+
+func TestSuperSprint(t *testing.T) {
+	cfg := testConfig()
+	cfg.RaceType = config.RaceSuperSprintQualification
+	cfg.FiringLines = 0
+	cfg.Finalists = 2
+
+	e, _ := runEvents(t, cfg,
+		"[09:00:00.000] 1 1",
+		"[09:00:00.000] 1 2",
+		"[09:00:00.000] 1 3",
+		"[09:10:00.000] 2 1 10:00:00.000",
+		"[09:10:00.000] 2 2 10:00:30.000",
+		"[09:10:00.000] 2 3 10:01:00.000",
+		"[09:59:00.000] 3 1",
+		"[10:00:00.000] 4 1",
+		"[10:00:00.000] 3 2",
+		"[10:00:30.000] 4 2",
+		"[10:00:30.000] 3 3",
+		"[10:01:00.000] 4 3",
+		"[10:05:00.000] 10 1",
+		"[10:05:40.000] 10 2",
+		"[10:05:30.000] 10 3",
+	)
+
+	var report strings.Builder
+	for _, row := range e.GetReport() {
+		report.WriteString(row.Format())
+	}
+	assert.Equal(t,
+		"[Finished] 1 [{05:00.000, 3.333}] {00:00.000, 0.000} 0/0 Q\n"+
+			"[Finished] 2 [{05:10.000, 3.226}] {00:00.000, 0.000} 0/0\n"+
+			"[Finished] 3 [{04:30.000, 3.704}] {00:00.000, 0.000} 0/0 Q\n",
+		report.String())
+
+	qualification, err := ParseReport(strings.NewReader(report.String()))
+	require.NoError(t, err)
+	starts := FinalStartList(qualification, cfg.Finalists, cfg.Start)
+	assert.Equal(t, map[int]time.Time{1: cfg.Start, 3: cfg.Start}, starts)
+
+	cfg.RaceType = config.RaceSuperSprintFinal
+	final := NewEngine(cfg, output.NewLogger(&bytes.Buffer{}), WithStartList(starts))
+	for _, event := range parseEvents(t,
+		"[09:00:00.000] 1 1",
+		"[09:00:00.000] 1 2",
+		"[09:10:00.000] 2 1 10:00:00.000",
+		"[09:10:00.000] 2 2 10:00:00.000",
+	) {
+		require.NoError(t, final.ProcessEvent(event))
+	}
+	rows := final.GetReport()
+	require.Len(t, rows, 2)
+	assert.Equal(t, 2, rows[1].CompetitorID, "undrawn competitors close the start list")
+	assert.Equal(t, []string{"competitor 2 is not on the start list"}, rows[1].Violations)
+}
+
+func TestLiveStatus(t *testing.T) {
+	cfg := testConfig()
+	cfg.Laps = 2
+	e := NewEngine(cfg, output.NewLogger(&bytes.Buffer{}))
+	lines := []string{
+		"[09:00:00.000] 1 1",
+		"[09:00:00.000] 1 2",
+		"[09:01:00.000] 2 1 10:00:00.000",
+		"[09:01:00.000] 2 2 10:00:30.000",
+		"[09:59:00.000] 3 1",
+		"[09:59:00.000] 3 2",
+		"[10:00:00.000] 4 1",
+		"[10:00:30.000] 4 2",
+	}
+	for _, id := range []string{"1", "2"} {
+		lines = append(lines, "[10:02:00.000] 5 "+id+" 1")
+		for target := 1; target <= 5; target++ {
+			lines = append(lines, fmt.Sprintf("[10:02:0%d.000] 6 %s %d", target, id, target))
+		}
+		lines = append(lines, "[10:02:10.000] 7 "+id)
+	}
+	lines = append(lines,
+		"[10:05:00.000] 10 1",
+		"[10:05:20.000] 10 2",
+		"[10:06:00.000] 5 1 1",
+	)
+	for _, event := range parseEvents(t, lines...) {
+		require.NoError(t, e.ProcessEvent(event))
+	}
+
+	assert.Equal(t, []StartListEntry{
+		{CompetitorID: 1, ScheduledStart: cfg.Start},
+		{CompetitorID: 2, ScheduledStart: cfg.Start.Add(30 * time.Second)},
+	}, e.StartList())
+
+	status, ok := e.Competitor(1)
+	require.True(t, ok)
+	assert.Equal(t, PhaseOnRange, status.Phase)
+	assert.True(t, status.OnRange)
+	assert.Equal(t, 1, status.LapsDone)
+	assert.Equal(t, []string{"XXXXX", "....."}, status.Shooting)
+	assert.Equal(t, 5, status.Hits)
+	assert.Equal(t, 10, status.Shots)
+
+	_, ok = e.Competitor(3)
+	assert.False(t, ok)
+
+	assert.Equal(t, []Standing{
+		{Rank: 1, CompetitorID: 2, Laps: 1, Stages: 1, Time: 4*time.Minute + 50*time.Second},
+		{Rank: 2, CompetitorID: 1, Laps: 1, Stages: 1, Time: 5 * time.Minute, Behind: 10 * time.Second},
+	}, e.Standings())
+}
+
+func TestStandings(t *testing.T) {
+	cfg := testConfig()
+	cfg.Laps = 2
+	lines := []string{
+		"[09:00:00.000] 1 1",
+		"[09:00:00.000] 1 2",
+		"[09:01:00.000] 2 1 10:00:00.000",
+		"[09:01:00.000] 2 2 10:00:30.000",
+		"[09:59:00.000] 3 1",
+		"[09:59:00.000] 3 2",
+		"[10:00:00.000] 4 1",
+		"[10:00:30.000] 4 2",
+		// stage 1: competitor 1 leaves at 3:00, competitor 2 at 2:50
+		"[10:02:50.000] 5 1 1",
+		"[10:03:00.000] 7 1",
+		"[10:03:10.000] 5 2 1",
+		"[10:03:20.000] 7 2",
+		// lap 1: competitor 1 at 5:00, competitor 2 at 5:10
+		"[10:04:00.000] 8 1",
+		"[10:04:30.000] 9 1",
+		"[10:05:00.000] 10 1",
+		"[10:04:30.000] 8 2",
+		"[10:05:00.000] 9 2",
+		"[10:05:40.000] 10 2",
+	}
+	e := NewEngine(cfg, output.NewLogger(&bytes.Buffer{}))
+	for _, event := range parseEvents(t, lines...) {
+		require.NoError(t, e.ProcessEvent(event))
+	}
+
+	at := func(clock string) time.Time {
+		parsed, err := time.Parse(events.TimeLayoutHMSMilli, clock)
+		require.NoError(t, err)
+		return parsed
+	}
+
+	assert.Equal(t, []Standing{
+		{Rank: 1, CompetitorID: 1, Stages: 1, Time: 3 * time.Minute},
+		{Rank: 2, CompetitorID: 2},
+	}, e.StandingsAt(at("10:03:05.000")), "competitor 2 is still before the range")
+
+	assert.Equal(t, []Standing{
+		{Rank: 1, CompetitorID: 1, Laps: 1, Stages: 1, Time: 5 * time.Minute},
+		{Rank: 2, CompetitorID: 2, Stages: 1, Time: 2*time.Minute + 50*time.Second},
+	}, e.StandingsAt(at("10:05:30.000")))
+
+	assert.Empty(t, e.StandingsAt(at("09:59:59.000")))
+
+	assert.Equal(t, []Standing{
+		{Rank: 1, CompetitorID: 2, Stages: 1, Time: 2*time.Minute + 50*time.Second},
+		{Rank: 2, CompetitorID: 1, Stages: 1, Time: 3 * time.Minute, Behind: 10 * time.Second},
+	}, e.StandingsAfter(Checkpoint{Stage: 1}))
+
+	assert.Equal(t, []Standing{
+		{Rank: 1, CompetitorID: 1, Laps: 1, Stages: 1, Time: 5 * time.Minute},
+		{Rank: 2, CompetitorID: 2, Laps: 1, Stages: 1, Time: 5*time.Minute + 10*time.Second, Behind: 10 * time.Second},
+	}, e.StandingsAfter(Checkpoint{Lap: 1}))
+
+	assert.Empty(t, e.StandingsAfter(Checkpoint{Lap: 2}))
+}
+
+func TestStandingsDropOutAndTies(t *testing.T) {
+	lines := []string{
+		"[09:00:00.000] 1 1",
+		"[09:00:00.000] 1 2",
+		"[09:00:00.000] 1 3",
+		"[09:01:00.000] 2 1 10:00:00.000",
+		"[09:01:00.000] 2 2 10:00:30.000",
+		"[09:01:00.000] 2 3 10:01:00.000",
+		"[09:59:00.000] 3 1",
+		"[09:59:00.000] 3 2",
+		"[09:59:00.000] 3 3",
+		"[10:00:00.000] 4 1",
+		"[10:00:30.000] 4 2",
+		"[10:01:00.000] 4 3",
+		// stage 1: competitors 1 and 2 both leave at 2:00, competitor 3 at 2:30
+		"[10:01:50.000] 5 1 1",
+		"[10:02:00.000] 7 1",
+		"[10:02:20.000] 5 2 1",
+		"[10:02:30.000] 7 2",
+		"[10:03:20.000] 5 3 1",
+		"[10:03:30.000] 7 3",
+		"[10:04:00.000] 11 3 Broke a ski",
+	}
+	e := NewEngine(testConfig(), output.NewLogger(&bytes.Buffer{}))
+	for _, event := range parseEvents(t, lines...) {
+		require.NoError(t, e.ProcessEvent(event))
+	}
+
+	at := func(clock string) time.Time {
+		parsed, err := time.Parse(events.TimeLayoutHMSMilli, clock)
+		require.NoError(t, err)
+		return parsed
+	}
+
+	assert.Equal(t, []Standing{
+		{Rank: 1, CompetitorID: 1, Stages: 1, Time: 2 * time.Minute},
+		{Rank: 1, CompetitorID: 2, Stages: 1, Time: 2 * time.Minute},
+		{Rank: 3, CompetitorID: 3, Stages: 1, Time: 2*time.Minute + 30*time.Second, Behind: 30 * time.Second},
+	}, e.StandingsAt(at("10:03:30.000")), "competitor 3 drops out later")
+
+	assert.Equal(t, []Standing{
+		{Rank: 1, CompetitorID: 1, Stages: 1, Time: 2 * time.Minute},
+		{Rank: 1, CompetitorID: 2, Stages: 1, Time: 2 * time.Minute},
+	}, e.StandingsAt(at("10:04:00.000")))
+
+	assert.Equal(t, []Standing{
+		{Rank: 1, CompetitorID: 1, Stages: 1, Time: 2 * time.Minute},
+		{Rank: 1, CompetitorID: 2, Stages: 1, Time: 2 * time.Minute},
+		{Rank: 3, CompetitorID: 3, Stages: 1, Time: 2*time.Minute + 30*time.Second, Behind: 30 * time.Second},
+	}, e.StandingsAfter(Checkpoint{Stage: 1}))
+}
+
+func TestParseCheckpoint(t *testing.T) {
+	cp, err := ParseCheckpoint("lap:2")
+	require.NoError(t, err)
+	assert.Equal(t, Checkpoint{Lap: 2}, cp)
+
+	cp, err = ParseCheckpoint("stage:1")
+	require.NoError(t, err)
+	assert.Equal(t, Checkpoint{Stage: 1}, cp)
+
+	for _, bad := range []string{"", "lap", "lap:0", "loop:1", "stage:x"} {
+		_, err := ParseCheckpoint(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestPenaltyStages(t *testing.T) {
+	cfg := testConfig()
+	cfg.Laps = 2
+	cfg.PenaltyMaxSpeed = 10
+	e, _ := runEvents(t, cfg,
+		"[09:00:00.000] 1 1",
+		"[09:01:00.000] 2 1 10:00:00.000",
+		"[09:59:00.000] 3 1",
+		"[10:00:00.000] 4 1",
+		// stage 1: two misses, both loops skied
+		"[10:02:00.000] 5 1 1",
+		"[10:02:05.000] 6 1 1",
+		"[10:02:10.000] 6 1 2",
+		"[10:02:15.000] 6 1 3",
+		"[10:02:30.000] 7 1",
+		"[10:02:35.000] 8 1",
+		"[10:03:05.000] 9 1",
+		"[10:05:00.000] 10 1",
+		// stage 2: clean
+		"[10:07:00.000] 5 1 1",
+		"[10:07:05.000] 6 1 1",
+		"[10:07:10.000] 6 1 2",
+		"[10:07:15.000] 6 1 3",
+		"[10:07:20.000] 6 1 4",
+		"[10:07:25.000] 6 1 5",
+		"[10:07:30.000] 7 1",
+		"[10:10:00.000] 10 1",
+	)
+	rows := e.GetReport()
+	require.Len(t, rows, 1)
+
+	assert.Equal(t, []PenaltyStage{
+		{Stage: 1, Range: 1, Lap: 1, Owed: 2, Time: 30 * time.Second, Speed: 200.0 / 30},
+	}, rows[0].PenaltyStages, "clean stages have no penalty block")
+	assert.Equal(t, 30*time.Second, rows[0].PenaltyTime, "the legacy block keeps the sum")
+}
+
+func TestPenaltyTimeWithOpenInterval(t *testing.T) {
+	cfg := testConfig()
+	cfg.PenaltyMaxSpeed = 10
+	e, _ := runEvents(t, cfg,
+		"[09:00:00.000] 1 1",
+		"[09:01:00.000] 2 1 10:00:00.000",
+		"[09:59:00.000] 3 1",
+		"[10:00:00.000] 4 1",
+		"[10:02:00.000] 5 1 1",
+		"[10:02:05.000] 6 1 1",
+		"[10:02:30.000] 7 1",
+		"[10:02:35.000] 8 1",
+		"[10:03:00.000] 11 1 Broke a ski",
+	)
+	rows := e.GetReport()
+	require.Len(t, rows, 1)
+	assert.Equal(t, StatusNotFinished, rows[0].Status)
+	assert.Zero(t, rows[0].PenaltyTime, "loops never left are not timed")
+}
+
+func TestLapBreakdown(t *testing.T) {
+	cfg := testConfig()
+	cfg.PenaltyMaxSpeed = 10
+	e, _ := runEvents(t, cfg,
+		"[09:00:00.000] 1 1",
+		"[09:01:00.000] 2 1 10:00:00.000",
+		"[09:59:00.000] 3 1",
+		"[10:00:00.000] 4 1",
+		"[10:02:00.000] 5 1 1",
+		"[10:02:05.000] 6 1 1",
+		"[10:02:10.000] 6 1 2",
+		"[10:02:15.000] 6 1 3",
+		"[10:02:20.000] 6 1 4",
+		"[10:02:30.000] 7 1",
+		"[10:02:35.000] 8 1",
+		"[10:02:55.000] 9 1",
+		"[10:05:00.000] 10 1",
+	)
+	rows := e.GetReport()
+	require.Len(t, rows, 1)
+	row := rows[0]
+
+	assert.Equal(t, []time.Duration{5 * time.Minute}, row.LapTimes)
+	assert.Equal(t, []time.Duration{30 * time.Second}, row.LapRangeTimes)
+	assert.Equal(t, []time.Duration{20 * time.Second}, row.LapPenaltyTimes)
+	assert.Equal(t, []time.Duration{4*time.Minute + 10*time.Second}, row.LapCourseTimes)
+	assert.Equal(t, []float64{4}, row.LapSkiSpeeds)
+	assert.Equal(t, []time.Duration{30 * time.Second}, row.StageRangeTimes)
+	assert.Equal(t, []PenaltyStage{
+		{Stage: 1, Range: 1, Lap: 1, Owed: 1, Time: 20 * time.Second, Speed: 5},
+	}, row.PenaltyStages)
+
+	assert.Equal(t, ""+
+		"    lap 1: course 04:10.000 (4.000), range 00:30.000, penalty 00:20.000\n"+
+		"    stage 1: XXXX. 00:30.000\n"+
+		"    penalty after stage 1: {00:20.000, 5.000} loops 1\n",
+		row.FormatSplits())
+}
+
+func TestShootingStages(t *testing.T) {
+	cfg := testConfig()
+	cfg.Laps = 2
+	e, _ := runEvents(t, cfg,
+		"[09:00:00.000] 1 1",
+		"[09:01:00.000] 2 1 10:00:00.000",
+		"[09:59:00.000] 3 1",
+		"[10:00:00.000] 4 1",
+		"[10:02:00.000] 5 1 1",
+		"[10:02:05.000] 6 1 1",
+		"[10:02:10.000] 6 1 3",
+		"[10:02:30.000] 7 1",
+		"[10:05:00.000] 10 1",
+		// still on the second range, not reported
+		"[10:07:00.000] 5 1 2",
+		"[10:07:05.000] 6 1 1",
+	)
+
+	stages := e.ShootingStages()
+	require.Len(t, stages, 1)
+	stage := stages[0]
+	assert.Equal(t, 1, stage.CompetitorID)
+	assert.Equal(t, 1, stage.Stage)
+	assert.Equal(t, 1, stage.Range)
+	assert.Equal(t, 1, stage.Lap)
+	assert.Equal(t, [5]bool{true, false, true, false, false}, stage.Targets)
+	assert.Len(t, stage.HitTimes, 2)
+	assert.Equal(t, 2, stage.Hits)
+	assert.Equal(t, 5, stage.Shots)
+}
+
+func TestShootingPositions(t *testing.T) {
+	cfg := testConfig()
+	cfg.RaceType = config.RaceRelay
+	cfg.Legs = 2
+	cfg.FiringLines = 2
+	cfg.Positions = []config.Position{config.PositionProne, config.PositionStanding}
+
+	e, _ := runEvents(t, cfg,
+		"[09:00:00.000] 1 1",
+		"[09:10:00.000] 2 1 10:00:00.000",
+		"[09:59:00.000] 3 1",
+		"[10:00:00.000] 4 1",
+		"[10:01:00.000] 5 1 1",
+		"[10:01:01.000] 6 1 1",
+		"[10:01:02.000] 6 1 2",
+		"[10:01:03.000] 6 1 3",
+		"[10:01:04.000] 6 1 4",
+		"[10:01:05.000] 6 1 5",
+		"[10:01:10.000] 7 1",
+		"[10:02:00.000] 5 1 2",
+		"[10:02:01.000] 6 1 1",
+		"[10:02:10.000] 7 1",
+		"[10:05:00.000] 10 1",
+		"[10:05:00.000] 12 1",
+		// the sequence restarts with the second leg
+		"[10:06:00.000] 5 1 1",
+		"[10:06:01.000] 6 1 1",
+		"[10:06:10.000] 7 1",
+	)
+
+	rows := e.GetReport()
+	require.Len(t, rows, 1)
+	team := rows[0]
+	assert.Equal(t, []config.Position{config.PositionProne, config.PositionStanding, config.PositionProne},
+		team.StagePositions)
+	assert.Equal(t, []PositionShooting{
+		{Position: config.PositionProne, Stages: 2, Hits: 6, Shots: 10},
+		{Position: config.PositionStanding, Stages: 1, Hits: 1, Shots: 5},
+	}, team.Positions)
+	require.Len(t, team.Legs, 2)
+	assert.Equal(t, []config.Position{config.PositionProne}, team.Legs[1].StagePositions)
+	assert.Contains(t, team.Legs[0].FormatSplits(), "    stage 2 (standing): X.... 00:10.000\n")
+	assert.Contains(t, team.FormatSplits(), "    positions: prone 6/10, standing 1/5\n")
+
+	stages := e.ShootingStages()
+	require.Len(t, stages, 3)
+	assert.Equal(t, config.PositionStanding, stages[1].Position)
+}
+
+func TestRoster(t *testing.T) {
+	roster := models.Roster{1: {CompetitorID: 1, Bib: "12", Name: "J. Doe", Nation: "NOR", Category: "Men"}}
+	lines := []string{
+		"[09:00:00.000] 1 1",
+		"[09:00:01.000] 1 2",
+	}
+
+	e := NewEngine(testConfig(), output.NewLogger(&bytes.Buffer{}), WithRoster(roster))
+	for _, event := range parseEvents(t, lines...) {
+		require.NoError(t, e.ProcessEvent(event))
+	}
+	rows := e.GetReport()
+	require.Len(t, rows, 2)
+	assert.Equal(t, roster[1], rows[0].Entry)
+	assert.Empty(t, rows[0].Violations)
+	assert.Zero(t, rows[1].Entry)
+	assert.Equal(t, []string{"competitor 2 is not on the roster"}, rows[1].Violations)
+
+	strict := NewEngine(testConfig(), output.NewLogger(&bytes.Buffer{}), WithRoster(roster), WithMode(ModeStrict))
+	events := parseEvents(t, lines...)
+	require.NoError(t, strict.ProcessEvent(events[0]))
+	assert.EqualError(t, strict.ProcessEvent(events[1]), "competitor 2 is not on the roster")
+}
+
+func TestPenaltyLoopBeforeRangeVisit(t *testing.T) {
+	e, _ := runEvents(t, testConfig(),
+		"[09:00:00.000] 1 1",
+		"[09:01:00.000] 2 1 10:00:00.000",
+		"[09:59:00.000] 3 1",
+		"[10:00:00.000] 4 1",
+		"[10:01:00.000] 8 1",
+		"[10:01:30.000] 9 1",
+		"[10:05:00.000] 10 1",
+	)
+	var rows []ReportRow
+	require.NotPanics(t, func() { rows = e.GetReport() })
+	require.Len(t, rows, 1)
+	assert.Empty(t, rows[0].PenaltyStages)
+}
+
+func TestPenaltyEnterWithoutRangeVisit(t *testing.T) {
+	lines := []string{
+		"[09:00:00.000] 1 1",
+		"[09:01:00.000] 2 1 10:00:00.000",
+		"[09:59:00.000] 3 1",
+		"[10:00:00.000] 4 1",
+		"[10:01:00.000] 8 1",
+	}
+	strict := NewEngine(testConfig(), output.NewLogger(&bytes.Buffer{}), WithMode(ModeStrict))
+	var err error
+	for _, event := range parseEvents(t, lines...) {
+		if err = strict.ProcessEvent(event); err != nil {
+			break
+		}
+	}
+	assert.EqualError(t, err, "competitor 1: penalty laps before any firing range visit")
+
+	e, _ := runEvents(t, testConfig(), lines...)
+	status, ok := e.Competitor(1)
+	require.True(t, ok)
+	assert.Equal(t, PhaseSkiing, status.Phase)
+	assert.Equal(t, []string{"competitor 1: penalty laps before any firing range visit"}, status.Violations)
+}
+
+func TestSkippedEventsLog(t *testing.T) {
+	var text, jsonl bytes.Buffer
+	e := NewEngine(testConfig(), output.NewMultiSink(output.NewLogger(&text), output.NewJSONLogger(&jsonl)))
+	for _, event := range parseEvents(t,
+		"[09:00:00.000] 1 1",
+		"[09:00:01.000] 1 1",
+		"[09:10:00.000] 2 1 10:00:00.000",
+		"[09:59:00.000] 3 1",
+		"[10:00:31.000] 4 1",
+	) {
+		require.NoError(t, e.ProcessEvent(event))
+	}
+
+	lines := strings.Split(strings.TrimSpace(jsonl.String()), "\n")
+	require.Len(t, lines, 6, jsonl.String())
+	assert.Contains(t, lines[1], `"event":"register","competitorId":1,"hits":0,"status":"rejected",`+
+		`"violation":"competitor 1: eventID=1 is not allowed while registered"`)
+	assert.Contains(t, lines[4], `"event":"disqualification"`)
+	assert.Contains(t, lines[5], `"event":"start","competitorId":1,"hits":0,"status":"ignored"`)
+	assert.NotContains(t, text.String(), "[09:00:01.000]", "the text log keeps accepted events only")
+}
+
+func TestRejectedFirstEvent(t *testing.T) {
+	e, log := runEvents(t, testConfig(),
+		"[09:00:00.000] 3 2",
+		"[09:00:00.000] 1 1",
+		"[09:10:00.000] 2 1 10:00:00.000",
+		"[09:59:00.000] 3 1",
+		"[10:00:00.000] 4 1",
+		"[10:05:00.000] 10 1",
+	)
+	status, ok := e.Competitor(2)
+	require.True(t, ok)
+	assert.Equal(t, []string{"competitor 2: eventID=3 is not allowed while unregistered"}, status.Violations)
+
+	rows := e.GetReport()
+	require.Len(t, rows, 2)
+	assert.Equal(t, 2, rows[1].CompetitorID, "competitors without a draw come last")
+	assert.Equal(t, StatusNotStarted, rows[1].Status)
+	assert.Equal(t, status.Violations, rows[1].Violations)
+	assert.NotContains(t, log, "competitor(2)", "a competitor who never registered is not disqualified")
+	assert.True(t, e.Closed())
+}
+
+func TestClosedWithoutStart(t *testing.T) {
+	var log bytes.Buffer
+	e := NewEngine(testConfig(), output.NewLogger(&log))
+	for _, event := range parseEvents(t,
+		"[09:00:00.000] 1 1",
+		"[09:00:00.000] 1 2",
+		"[09:01:00.000] 2 1 10:00:00.000",
+		"[09:01:00.000] 2 2 10:01:00.000",
+		"[09:59:00.000] 3 1",
+		"[10:00:00.000] 4 1",
+	) {
+		require.NoError(t, e.ProcessEvent(event))
+	}
+	assert.False(t, e.Closed(), "competitors are on the course")
+
+	require.NoError(t, e.ProcessEvent(parseEvents(t, "[10:01:00.000] 10 1")[0]))
+	written := log.Len()
+	assert.False(t, e.Closed(), "the start window of competitor 2 is still open")
+	assert.Equal(t, written, log.Len(), "checking the race writes no events")
+
+	require.NoError(t, e.ProcessEvent(parseEvents(t, "[10:05:00.000] 11 1 Broken ski")[0]))
+	assert.True(t, e.Closed(), "competitor 2 never started")
+	status, ok := e.Competitor(2)
+	require.True(t, ok)
+	assert.Equal(t, PhaseDisqualified, status.Phase)
+}
+
+func TestInvalidDraw(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		wantErr string
+	}{
+		{
+			name:    "missing start time",
+			line:    "[09:10:00.000] 2 1",
+			wantErr: "competitor 1: invalid start time: missing parameter for eventID=2",
+		},
+		{
+			name:    "unparsable start time",
+			line:    "[09:10:00.000] 2 1 ten",
+			wantErr: `competitor 1: invalid start time: parsing time "ten" as "15:04:05.000": cannot parse "ten" as "15"`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			lines := []string{"[09:00:00.000] 1 1", tc.line}
+			e, log := runEvents(t, testConfig(), lines...)
+			status, ok := e.Competitor(1)
+			require.True(t, ok)
+			assert.Equal(t, PhaseDisqualified, status.Phase)
+			assert.Equal(t, []string{tc.wantErr}, status.Violations)
+			assert.NotContains(t, log, "set by a draw")
+
+			strict := NewEngine(testConfig(), output.NewLogger(&bytes.Buffer{}), WithMode(ModeStrict))
+			var err error
+			for _, event := range parseEvents(t, lines...) {
+				if err = strict.ProcessEvent(event); err != nil {
+					break
+				}
+			}
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
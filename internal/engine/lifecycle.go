@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/models"
+)
+
+// Phase is a step of the competitor lifecycle.
+type Phase int
+
+const (
+	PhaseUnregistered Phase = iota
+	PhaseRegistered
+	PhaseDrawn
+	PhaseOnLine
+	PhaseSkiing
+	PhaseOnRange
+	PhaseInPenalty
+	PhaseFinished
+	PhaseNotFinished
+	PhaseDisqualified
+)
+
+var phaseNames = [...]string{
+	PhaseUnregistered: "unregistered",
+	PhaseRegistered:   "registered",
+	PhaseDrawn:        "drawn",
+	PhaseOnLine:       "on start line",
+	PhaseSkiing:       "skiing",
+	PhaseOnRange:      "on firing range",
+	PhaseInPenalty:    "in penalty laps",
+	PhaseFinished:     "finished",
+	PhaseNotFinished:  "not finished",
+	PhaseDisqualified: "disqualified",
+}
+
+func (p Phase) String() string {
+	if p < 0 || int(p) >= len(phaseNames) {
+		return fmt.Sprintf("Phase(%d)", int(p))
+	}
+	return phaseNames[p]
+}
+
+func (p Phase) terminal() bool {
+	return p == PhaseFinished || p == PhaseNotFinished || p == PhaseDisqualified
+}
+
+// TransitionError is returned for an event the competitor can`t receive in
+// its current phase.
+type TransitionError struct {
+	CompetitorID int
+	Phase        Phase
+	EventID      models.EventID
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("competitor %d: eventID=%d is not allowed while %s",
+		e.CompetitorID, e.EventID, e.Phase)
+}
+
+// nextPhase returns the phase the competitor moves to after the incoming
+// event. Events unknown to the lifecycle leave the phase unchanged.
+func nextPhase(p Phase, id models.EventID) (Phase, bool) {
+	switch id {
+	case models.EventRegister:
+		return PhaseRegistered, p == PhaseUnregistered
+	case models.EventDraw:
+		return PhaseDrawn, p == PhaseRegistered
+	case models.EventOnLine:
+		return PhaseOnLine, p == PhaseDrawn
+	case models.EventStart:
+		return PhaseSkiing, p == PhaseOnLine
+	case models.EventFiring:
+		return PhaseOnRange, p == PhaseSkiing
+	case models.EventHit:
+		return PhaseOnRange, p == PhaseOnRange
+	case models.EventLeaveFiring:
+		return PhaseSkiing, p == PhaseOnRange
+	case models.EventPenaltyEnter:
+		return PhaseInPenalty, p == PhaseSkiing
+	case models.EventPenaltyLeave:
+		return PhaseSkiing, p == PhaseInPenalty
+	case models.EventLapEnd:
+		return PhaseSkiing, p == PhaseSkiing
+	case models.EventExchange:
+		return PhaseSkiing, p == PhaseSkiing
+	case models.EventSpareRound:
+		return PhaseOnRange, p == PhaseOnRange
+	case models.EventNotContinue:
+		return PhaseNotFinished, p != PhaseUnregistered && !p.terminal()
+	default:
+		return p, true
+	}
+}
@@ -0,0 +1,79 @@
+package engine
+
+import "time"
+
+// StartListEntry is a drawn competitor on the start list.
+type StartListEntry struct {
+	CompetitorID   int
+	ScheduledStart time.Time
+}
+
+// StartList returns drawn competitors in start order.
+func (e *Engine) StartList() []StartListEntry {
+	var entries []StartListEntry
+	for _, st := range e.sortedStates() {
+		if st.ScheduledStart.IsZero() {
+			continue
+		}
+		entries = append(entries, StartListEntry{CompetitorID: st.CompetitorID, ScheduledStart: st.ScheduledStart})
+	}
+	return entries
+}
+
+// CompetitorStatus is a snapshot of a competitor during the race.
+type CompetitorStatus struct {
+	CompetitorID   int
+	Phase          Phase
+	ScheduledStart time.Time
+	ActualStart    time.Time
+	LapsDone       int
+	OnRange        bool
+	InPenalty      bool
+	Hits           int
+	Shots          int
+	Shooting       []string // hit/miss pattern per firing range visit so far
+	Violations     []string
+}
+
+// Competitor returns the current status of the competitor.
+func (e *Engine) Competitor(id int) (CompetitorStatus, bool) {
+	st, ok := e.states[id]
+	if !ok {
+		return CompetitorStatus{}, false
+	}
+	status := CompetitorStatus{
+		CompetitorID:   st.CompetitorID,
+		Phase:          st.Phase,
+		ScheduledStart: st.ScheduledStart,
+		ActualStart:    st.ActualStart,
+		LapsDone:       len(st.LapEndTimes),
+		OnRange:        st.Phase == PhaseOnRange,
+		InPenalty:      st.Phase == PhaseInPenalty,
+		Violations:     st.Violations,
+	}
+	for i := range st.RangeVisits {
+		visit := &st.RangeVisits[i]
+		status.Shooting = append(status.Shooting, visit.pattern())
+		status.Hits += visit.hits()
+		status.Shots += visit.shots()
+	}
+	return status, true
+}
+
+// Closed reports whether the race is over: every registered competitor has
+// finished, dropped out or been disqualified. It doesn't change the engine:
+// a competitor who never started is disqualified by ProcessEvent once an
+// event comes in after the start window, and keeps the race open until then.
+func (e *Engine) Closed() bool {
+	registered := 0
+	for _, st := range e.states {
+		if st.Phase == PhaseUnregistered {
+			continue // only rejected events so far
+		}
+		if !st.Phase.terminal() {
+			return false
+		}
+		registered++
+	}
+	return registered > 0
+}
@@ -0,0 +1,35 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+)
+
+// checkLane verifies the mass start lane assignment: on the first stage lanes
+// follow bib order, on every later stage they follow arrival order.
+func (e *Engine) checkLane(state *competitorState, lane int) error {
+	stage := len(state.RangeVisits)
+	e.arrivals[stage]++
+
+	expected := e.arrivals[stage]
+	if stage == 0 {
+		expected = e.bibLane(state.CompetitorID)
+	}
+	if lane == expected {
+		return nil
+	}
+	return e.flag(state, fmt.Errorf("competitor %d: stage %d shot from lane %d, expected lane %d",
+		state.CompetitorID, stage+1, lane, expected))
+}
+
+// bibLane returns the position of the competitor in the start list ordered by bib.
+func (e *Engine) bibLane(competitorID int) int {
+	var bibs []int
+	for id, st := range e.states {
+		if !st.ScheduledStart.IsZero() {
+			bibs = append(bibs, id)
+		}
+	}
+	sort.Ints(bibs)
+	return sort.SearchInts(bibs, competitorID) + 1
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/config"
+	"github.com/zahartd/biathlon_competitions_system/internal/models"
+)
+
+// StartListFor builds the start list of a race that starts from a previous
+// result: the pursuit handicaps or the super sprint final. The previous
+// report is read from previousPath. Other races start from draws and get a
+// nil list.
+func StartListFor(cfg config.Config, previousPath string) (map[int]time.Time, error) {
+	if cfg.RaceType != config.RacePursuit && cfg.RaceType != config.RaceSuperSprintFinal {
+		return nil, nil
+	}
+	f, err := os.Open(previousPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	previous, err := ParseReport(f)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.RaceType == config.RaceSuperSprintFinal {
+		return FinalStartList(previous, cfg.Finalists, cfg.Start), nil
+	}
+	return PursuitStartList(previous, cfg.Start), nil
+}
+
+// PursuitStartList turns a previous race result into handicap start times:
+// the winner starts at start, everybody else as far behind as they finished.
+// Competitors without a result are not on the list.
+func PursuitStartList(rows []ReportRow, start time.Time) map[int]time.Time {
+	starts := make(map[int]time.Time)
+	for _, row := range rows {
+		if row.ranked() {
+			starts[row.CompetitorID] = start.Add(row.Behind)
+		}
+	}
+	return starts
+}
+
+// pullLapped removes competitors who are a full lap behind the competitor
+// that has just ended a lap.
+func (e *Engine) pullLapped(leader *competitorState, now time.Time) {
+	laps := len(leader.LapEndTimes)
+	for _, st := range e.sortedStates() {
+		if st == leader || !st.racing() || len(st.LapEndTimes) >= laps-1 {
+			continue
+		}
+		st.Lapped = true
+		st.NotFinished = true
+		st.OutTime = now
+		st.NotFinishedMsg = "lapped"
+		st.Phase = PhaseNotFinished
+		e.write(models.Event{
+			Time:         now,
+			ID:           models.EventLapped,
+			CompetitorID: st.CompetitorID,
+			ExtraParams:  []string{strconv.Itoa(laps)},
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/config"
+	"github.com/zahartd/biathlon_competitions_system/internal/models"
+)
+
+// relaySpareRounds is the number of rounds a relay athlete may load by hand
+// on every stage after the first five.
+const relaySpareRounds = 3
+
+// raceLaps returns the number of main laps a competitor or a relay team
+// skis to finish.
+func (e *Engine) raceLaps() int {
+	if e.cfg.RaceType.Relay() {
+		return e.legFirstLap(e.cfg.Legs)
+	}
+	return e.cfg.Laps
+}
+
+// legFirstLap returns the zero-based main lap the relay leg starts with.
+func (e *Engine) legFirstLap(leg int) int {
+	laps := 0
+	for i := 0; i < leg; i++ {
+		laps += e.cfg.LapsOfLeg(i)
+	}
+	return laps
+}
+
+// validateRelay checks relay specific events and that every leg ends with
+// an exchange.
+func (e *Engine) validateRelay(state *competitorState, event models.Event) error {
+	relay := e.cfg.RaceType.Relay()
+	legEnd := e.legFirstLap(state.Leg + 1)
+
+	switch event.ID {
+	case models.EventExchange:
+		if !relay {
+			return fmt.Errorf("competitor %d: exchanges happen only in relays", event.CompetitorID)
+		}
+		if len(state.LapEndTimes) != legEnd || state.Leg+1 >= e.cfg.Legs {
+			return fmt.Errorf("competitor %d: leg %d can`t hand over after %d laps",
+				event.CompetitorID, state.Leg+1, len(state.LapEndTimes))
+		}
+	case models.EventSpareRound:
+		if !relay {
+			return fmt.Errorf("competitor %d: spare rounds are loaded only in relays", event.CompetitorID)
+		}
+		if state.currentVisit().Spares == relaySpareRounds {
+			return fmt.Errorf("competitor %d: all %d spare rounds are already loaded",
+				event.CompetitorID, relaySpareRounds)
+		}
+	case models.EventLapEnd:
+		if relay && len(state.LapEndTimes) == legEnd {
+			return fmt.Errorf("competitor %d: leg %d must hand over before the next lap",
+				event.CompetitorID, state.Leg+1)
+		}
+	}
+	return nil
+}
+
+// legRows splits a relay team result into one row per leg the team has
+// started. In a single mixed relay the two athletes of a team alternate.
+func (e *Engine) legRows(state *competitorState, teamStatus string) []ReportRow {
+	var legs []ReportRow
+	for leg := 0; leg <= state.Leg; leg++ {
+		from, laps := e.legFirstLap(leg), e.cfg.LapsOfLeg(leg)
+		row := ReportRow{
+			CompetitorID: state.CompetitorID,
+			Leg:          leg + 1,
+			Status:       teamStatus,
+		}
+		if e.cfg.RaceType == config.RaceSingleMixedRelay {
+			row.Athlete = leg%2 + 1
+		}
+		e.fillSplits(&row, state, from, from+laps)
+		if len(row.LapTimes) == laps {
+			row.Status = StatusFinished
+		}
+		legs = append(legs, row)
+	}
+	return legs
+}
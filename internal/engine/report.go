@@ -1,37 +1,397 @@
 package engine
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/config"
+	"github.com/zahartd/biathlon_competitions_system/internal/models"
+)
+
+const (
+	StatusFinished    = "Finished"
+	StatusNotFinished = "NotFinished"
+	StatusNotStarted  = "NotStarted"
 )
 
 type ReportRow struct {
-	CompetitorID   int
-	Status         string
-	LapTimes       []time.Duration
-	LapSpeeds      []float64
-	PenaltyTime    time.Duration
-	PenaltySpeed   float64
-	Hits           int
-	Shots          int
-	ScheduledStart time.Time // aux info for sorting, not for report
+	CompetitorID    int
+	Status          string
+	Rank            int           // place among finishers, 0 if not ranked
+	TotalTime       time.Duration // net race time including time penalties
+	Behind          time.Duration // gap to the leader
+	LapTimes        []time.Duration
+	LapSpeeds       []float64
+	LapCourseTimes  []time.Duration // lap time without range and penalty loops
+	LapRangeTimes   []time.Duration // time on firing ranges during the lap
+	LapPenaltyTimes []time.Duration // time on penalty loops of the lap's ranges
+	LapSkiSpeeds    []float64       // lap length over course time
+	PenaltyTime     time.Duration
+	PenaltySpeed    float64
+	PenaltyStages   []PenaltyStage // penalty loops per shooting stage, PenaltyTime is their sum
+	TimedPenalty    bool           // penalty block holds time added per miss, speed is empty
+	TimePenalty     time.Duration  // added for penalty laps that were not skied
+	Hits            int
+	Shots           int
+	Shooting        []string           // hit/miss pattern per firing range visit, e.g. "XX.XX"
+	StageLaps       []int              // lap of the row per firing range visit, starting at 1
+	StageRangeTimes []time.Duration    // time on the range per firing range visit
+	StagePositions  []config.Position  // shooting position per firing range visit, empty without a sequence
+	Positions       []PositionShooting // hits and shots per shooting position, prone first
+	Violations      []string           // rule violations recorded in lenient mode
+	ScheduledStart  time.Time          // aux info for sorting, not for report
+	ActualStart     time.Time          // zero if the competitor did not start
+	Leg             int                // relay leg number of a nested leg row
+	Athlete         int                // single mixed relay: team athlete skiing the leg
+	Legs            []ReportRow        // relay team: one row per leg
+	Qualified       bool               // super sprint qualification: made the final
+	Disqualified    bool               // started outside the start window, reported as NotStarted
+	NotFinishedMsg  string             // why the competitor did not finish, e.g. "lapped"
+	Entry           models.RosterEntry // zero without a roster entry
+}
+
+// PenaltyStage is the penalty loop block that followed one shooting stage.
+type PenaltyStage struct {
+	Stage   int // shooting stage of the row, starting at 1
+	Range   int // firing range number
+	Lap     int // main lap of the stage, starting at 1
+	Owed    int // penalty loops owed for the misses
+	Skipped int // owed loops not skied, known once the competitor moved on
+	Time    time.Duration
+	Speed   float64 // PenaltyLen * Owed / Time
+}
+
+// PositionShooting sums up the stages a row shot in one position.
+type PositionShooting struct {
+	Position config.Position
+	Stages   int
+	Hits     int
+	Shots    int
 }
 
 func (r ReportRow) Format() string {
+	return r.line() + r.qualifiedMark() + "\n" + r.formatLegs()
+}
+
+// FormatResult renders the row for the result list: the legacy line prefixed
+// with the place and followed by the total time and the gap to the leader.
+func (r ReportRow) FormatResult() string {
+	if !r.ranked() {
+		return fmt.Sprintf("-. %s%s\n", r.line(), r.qualifiedMark()) + r.formatLegs()
+	}
+	return fmt.Sprintf("%d. %s %s +%s%s\n",
+		r.Rank, r.line(), FormatDuration(r.TotalTime), FormatDuration(r.Behind), r.qualifiedMark()) +
+		r.formatLegs()
+}
+
+func (r ReportRow) qualifiedMark() string {
+	if r.Qualified {
+		return " Q"
+	}
+	return ""
+}
+
+// line renders the row without nested legs. A relay team line shows the team
+// time instead of lap times.
+func (r ReportRow) line() string {
+	penStr := fmt.Sprintf("{%s, %.3f}", FormatDuration(r.PenaltyTime), r.PenaltySpeed)
+	if r.TimedPenalty {
+		penStr = fmt.Sprintf("{%s, }", FormatDuration(r.PenaltyTime))
+	}
+	if len(r.Legs) > 0 {
+		return fmt.Sprintf("[%s] %d %s %s %d/%d",
+			r.Status, r.CompetitorID, FormatDuration(r.teamTime()), penStr, r.Hits, r.Shots)
+	}
+	return fmt.Sprintf("[%s] %d [%s] %s %d/%d",
+		r.Status, r.CompetitorID, r.formatLaps(), penStr, r.Hits, r.Shots)
+}
+
+// FormatSplits renders the lap breakdown of the row: skiing, range and
+// penalty loop time with the pure skiing speed per lap, the range time per
+// shooting stage with its position, hits per position and the penalty loops
+// after each stage.
+func (r ReportRow) FormatSplits() string {
+	var b strings.Builder
+	for i := range r.LapCourseTimes {
+		fmt.Fprintf(&b, "    lap %d: course %s (%.3f), range %s, penalty %s\n",
+			i+1, FormatDuration(r.LapCourseTimes[i]), r.LapSkiSpeeds[i],
+			FormatDuration(r.LapRangeTimes[i]), FormatDuration(r.LapPenaltyTimes[i]))
+	}
+	for i, rangeTime := range r.StageRangeTimes {
+		position := ""
+		if i < len(r.StagePositions) {
+			position = fmt.Sprintf(" (%s)", r.StagePositions[i])
+		}
+		fmt.Fprintf(&b, "    stage %d%s: %s %s\n", i+1, position, r.Shooting[i], FormatDuration(rangeTime))
+	}
+	if len(r.Positions) > 0 {
+		var positions []string
+		for _, ps := range r.Positions {
+			positions = append(positions, fmt.Sprintf("%s %d/%d", ps.Position, ps.Hits, ps.Shots))
+		}
+		fmt.Fprintf(&b, "    positions: %s\n", strings.Join(positions, ", "))
+	}
+	for _, ps := range r.PenaltyStages {
+		fmt.Fprintf(&b, "    penalty after stage %d: {%s, %.3f} loops %d",
+			ps.Stage, FormatDuration(ps.Time), ps.Speed, ps.Owed)
+		if ps.Skipped > 0 {
+			fmt.Fprintf(&b, ", skipped %d", ps.Skipped)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func (r ReportRow) formatLaps() string {
 	var lapStrs []string
 	for i, d := range r.LapTimes {
-		lapStrs = append(lapStrs, fmt.Sprintf("{%s, %.3f}", formatDuration(d), r.LapSpeeds[i]))
+		lapStrs = append(lapStrs, fmt.Sprintf("{%s, %.3f}", FormatDuration(d), r.LapSpeeds[i]))
+	}
+	return strings.Join(lapStrs, ", ")
+}
+
+func (r ReportRow) formatLegs() string {
+	var b strings.Builder
+	for _, leg := range r.Legs {
+		athlete := ""
+		if leg.Athlete > 0 {
+			athlete = fmt.Sprintf(" (athlete %d)", leg.Athlete)
+		}
+		fmt.Fprintf(&b, "    leg %d%s: [%s] {%s, %.3f} %d/%d\n",
+			leg.Leg, athlete, leg.formatLaps(), FormatDuration(leg.PenaltyTime), leg.PenaltySpeed, leg.Hits, leg.Shots)
 	}
-	laps := strings.Join(lapStrs, ", ")
-	penStr := fmt.Sprintf("{%s, %.3f}", formatDuration(r.PenaltyTime), r.PenaltySpeed)
-	return fmt.Sprintf("[%s] %d [%s] %s %d/%d\n",
-		r.Status, r.CompetitorID, laps, penStr, r.Hits, r.Shots)
+	return b.String()
 }
 
-func formatDuration(d time.Duration) string {
+// teamTime returns the team result, or the time skied so far without one.
+func (r ReportRow) teamTime() time.Duration {
+	if r.TotalTime > 0 {
+		return r.TotalTime
+	}
+	var total time.Duration
+	for _, d := range r.LapTimes {
+		total += d
+	}
+	return total
+}
+
+// FormatDuration renders a duration as MM:SS.sss, the way the report does.
+func FormatDuration(d time.Duration) string {
 	ms := d.Milliseconds() % 1000
 	s := int(d.Seconds()) % 60
 	m := int(d.Minutes())
 	return fmt.Sprintf("%02d:%02d.%03d", m, s, ms)
 }
+
+// ranked reports whether the row takes part in the result list.
+func (r ReportRow) ranked() bool {
+	return r.Status == StatusFinished && r.TotalTime > 0
+}
+
+// rankRows assigns places and gaps to the leader to finishers. Equal times
+// share a place and the next place is skipped.
+func rankRows(rows []ReportRow) {
+	var finishers []*ReportRow
+	for i := range rows {
+		if rows[i].ranked() {
+			finishers = append(finishers, &rows[i])
+		}
+	}
+	sort.SliceStable(finishers, func(i, j int) bool {
+		return finishers[i].TotalTime < finishers[j].TotalTime
+	})
+	for i, row := range finishers {
+		row.Rank = i + 1
+		if i > 0 && row.TotalTime == finishers[i-1].TotalTime {
+			row.Rank = finishers[i-1].Rank
+		}
+		row.Behind = row.TotalTime - finishers[0].TotalTime
+	}
+}
+
+// SortByResult reorders start list rows into result order: ranked finishers,
+// then competitors without a result, NotFinished and NotStarted ones.
+// Rows within each group keep their start list order.
+func SortByResult(rows []ReportRow) {
+	group := func(r ReportRow) int {
+		switch {
+		case r.ranked():
+			return 0
+		case r.Status == StatusFinished:
+			return 1
+		case r.Status == StatusNotFinished:
+			return 2
+		default:
+			return 3
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		gi, gj := group(rows[i]), group(rows[j])
+		if gi != gj {
+			return gi < gj
+		}
+		return gi == 0 && rows[i].Rank < rows[j].Rank
+	})
+}
+
+// RowFilter selects report rows by roster entry. Empty fields match any row.
+type RowFilter struct {
+	Nation   string
+	Category string
+}
+
+// Apply returns the rows the filter matches, keeping their order and places.
+func (f RowFilter) Apply(rows []ReportRow) []ReportRow {
+	var kept []ReportRow
+	for _, row := range rows {
+		if (f.Nation == "" || strings.EqualFold(row.Entry.Nation, f.Nation)) &&
+			(f.Category == "" || strings.EqualFold(row.Entry.Category, f.Category)) {
+			kept = append(kept, row)
+		}
+	}
+	return kept
+}
+
+// GroupBy names the roster field report rows are grouped by.
+type GroupBy string
+
+const (
+	GroupByNation   GroupBy = "nation"
+	GroupByCategory GroupBy = "category"
+)
+
+// RowGroup is the rows sharing a roster field value.
+type RowGroup struct {
+	Key  string // empty for rows without the field
+	Rows []ReportRow
+}
+
+// GroupRows splits the rows into groups sorted by key, rows without the
+// field last. Rows keep their order and places within a group.
+func GroupRows(rows []ReportRow, by GroupBy) ([]RowGroup, error) {
+	var key func(ReportRow) string
+	switch by {
+	case GroupByNation:
+		key = func(r ReportRow) string { return r.Entry.Nation }
+	case GroupByCategory:
+		key = func(r ReportRow) string { return r.Entry.Category }
+	default:
+		return nil, fmt.Errorf("unknown grouping %q", by)
+	}
+
+	var groups []RowGroup
+	index := make(map[string]int)
+	for _, row := range rows {
+		k := key(row)
+		i, ok := index[k]
+		if !ok {
+			i = len(groups)
+			index[k] = i
+			groups = append(groups, RowGroup{Key: k})
+		}
+		groups[i].Rows = append(groups[i].Rows, row)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Key == "" || groups[j].Key == "" {
+			return groups[j].Key == "" && groups[i].Key != ""
+		}
+		return groups[i].Key < groups[j].Key
+	})
+	return groups, nil
+}
+
+var (
+	reportLineRe = regexp.MustCompile(
+		`^(?:(?:\d+|-)\. )?\[(\w+)\] (\d+) \[(.*)\] \{([^,]*), ([^}]*)\} (\d+)/(\d+)(?: (\S+) \+\S+)?( Q)?$`)
+	reportLapRe = regexp.MustCompile(`\{([^,]*), ([^}]*)\}`)
+)
+
+// ParseReport reads a report printed by Format or FormatResult back into
+// ranked rows. For the start list format the total time of a finisher is the
+// sum of its lap times, plus the penalty block when it holds added time.
+func ParseReport(r io.Reader) ([]ReportRow, error) {
+	var rows []ReportRow
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		row, err := parseReportRow(line)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	rankRows(rows)
+	return rows, nil
+}
+
+func parseReportRow(line string) (ReportRow, error) {
+	m := reportLineRe.FindStringSubmatch(line)
+	if m == nil {
+		return ReportRow{}, fmt.Errorf("invalid report line: %s", line)
+	}
+
+	row := ReportRow{Status: m[1]}
+	row.CompetitorID, _ = strconv.Atoi(m[2])
+	for _, lap := range reportLapRe.FindAllStringSubmatch(m[3], -1) {
+		lapTime, err := parseReportDuration(lap[1])
+		if err != nil {
+			return ReportRow{}, fmt.Errorf("invalid lap time in report line %s: %w", line, err)
+		}
+		speed, err := strconv.ParseFloat(lap[2], 64)
+		if err != nil {
+			return ReportRow{}, fmt.Errorf("invalid lap speed in report line %s: %w", line, err)
+		}
+		row.LapTimes = append(row.LapTimes, lapTime)
+		row.LapSpeeds = append(row.LapSpeeds, speed)
+	}
+
+	var err error
+	if row.PenaltyTime, err = parseReportDuration(m[4]); err != nil {
+		return ReportRow{}, fmt.Errorf("invalid penalty time in report line %s: %w", line, err)
+	}
+	if m[5] == "" {
+		row.TimedPenalty = true
+	} else if row.PenaltySpeed, err = strconv.ParseFloat(m[5], 64); err != nil {
+		return ReportRow{}, fmt.Errorf("invalid penalty speed in report line %s: %w", line, err)
+	}
+	row.Hits, _ = strconv.Atoi(m[6])
+	row.Shots, _ = strconv.Atoi(m[7])
+	row.Qualified = m[9] != ""
+	switch {
+	case m[8] != "":
+		if row.TotalTime, err = parseReportDuration(m[8]); err != nil {
+			return ReportRow{}, fmt.Errorf("invalid total time in report line %s: %w", line, err)
+		}
+	case row.Status == StatusFinished:
+		for _, lapTime := range row.LapTimes {
+			row.TotalTime += lapTime
+		}
+		if row.TimedPenalty {
+			row.TotalTime += row.PenaltyTime
+		}
+	}
+	return row, nil
+}
+
+// parseReportDuration is the inverse of FormatDuration.
+func parseReportDuration(str string) (time.Duration, error) {
+	var m, s, ms int
+	if _, err := fmt.Sscanf(str, "%d:%d.%d", &m, &s, &ms); err != nil {
+		return 0, err
+	}
+	return time.Duration(m)*time.Minute +
+		time.Duration(s)*time.Second +
+		time.Duration(ms)*time.Millisecond, nil
+}
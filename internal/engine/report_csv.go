@@ -0,0 +1,239 @@
+package engine
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/config"
+)
+
+// CSVLayout selects how WriteCSV lays out the report.
+type CSVLayout string
+
+const (
+	CSVWide CSVLayout = "wide" // one row per competitor, a column block per lap and range
+	CSVLong CSVLayout = "long" // one row per competitor lap
+)
+
+// WriteCSV writes the report rows as CSV. The lap and shooting columns follow
+// the laps and firing lines of the race, widened if a row has more laps or
+// more visits in a lap, so every row has the same columns. A shooting stage
+// sits in the column of its lap and its order within the lap. Relay legs
+// follow their team with the leg column filled.
+func WriteCSV(w io.Writer, rows []ReportRow, cfg config.Config, layout CSVLayout) error {
+	laps := cfg.Laps
+	if cfg.RaceType.Relay() {
+		laps = 0
+		for leg := range cfg.Legs {
+			laps += cfg.LapsOfLeg(leg)
+		}
+	}
+	perLap := cfg.FiringLines
+	for _, row := range flattenLegs(rows) {
+		stages := lapStages(row)
+		laps = max(laps, len(row.LapTimes), len(stages))
+		for _, lap := range stages {
+			perLap = max(perLap, len(lap))
+		}
+	}
+
+	var records [][]string
+	switch layout {
+	case CSVWide:
+		records = wideRecords(rows, laps, perLap)
+	case CSVLong:
+		records = longRecords(rows, perLap)
+	default:
+		return fmt.Errorf("unknown CSV layout %q", layout)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(records); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+var csvHeader = []string{"competitor", "leg", "status", "rank", "total_time", "behind", "scheduled_start", "actual_start",
+	"bib", "name", "nation", "team", "category"}
+
+var csvTotalsHeader = []string{"penalty_time", "penalty_speed", "time_penalty", "hits", "shots",
+	"prone_hits", "prone_shots", "standing_hits", "standing_shots"}
+
+func wideRecords(rows []ReportRow, laps, perLap int) [][]string {
+	header := append([]string(nil), csvHeader...)
+	for lap := 1; lap <= laps; lap++ {
+		header = append(header, csvLapHeader(fmt.Sprintf("lap%d_", lap))...)
+	}
+	header = append(header, csvTotalsHeader...)
+	for r := 1; r <= laps*perLap; r++ {
+		header = append(header, csvStageHeader(fmt.Sprintf("range%d", r))...)
+	}
+
+	records := [][]string{header}
+	for _, row := range flattenLegs(rows) {
+		record := csvRowFields(row)
+		for lap := range laps {
+			record = append(record, csvLap(row, lap)...)
+		}
+		record = append(record, csvTotals(row)...)
+		stages := lapStages(row)
+		for lap := range laps {
+			for r := range perLap {
+				record = append(record, csvStage(row, stageAt(stages, lap, r))...)
+			}
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func longRecords(rows []ReportRow, perLap int) [][]string {
+	header := append([]string(nil), csvHeader...)
+	header = append(header, "lap")
+	header = append(header, csvLapHeader("lap_")...)
+	for r := 1; r <= perLap; r++ {
+		header = append(header, csvStageHeader(fmt.Sprintf("range%d", r))...)
+	}
+
+	records := [][]string{header}
+	for _, row := range flattenLegs(rows) {
+		stages := lapStages(row)
+		laps := max(len(row.LapTimes), len(stages))
+		if laps == 0 {
+			// keep competitors without laps, e.g. NotStarted ones, in the list
+			record := append(csvRowFields(row), "")
+			record = append(record, csvLap(row, 0)...)
+			records = append(records, append(record, make([]string, perLap*len(csvStageHeader("")))...))
+			continue
+		}
+		for lap := range laps {
+			record := csvRowFields(row)
+			record = append(record, strconv.Itoa(lap+1))
+			record = append(record, csvLap(row, lap)...)
+			for r := range perLap {
+				record = append(record, csvStage(row, stageAt(stages, lap, r))...)
+			}
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// lapStages groups the firing range visits of the row by zero-based lap, in
+// visit order. Visits without a lap, e.g. of rows read back from a text
+// report, count to the first lap.
+func lapStages(r ReportRow) [][]int {
+	var laps [][]int
+	for visit := range r.Shooting {
+		lap := 0
+		if visit < len(r.StageLaps) {
+			lap = max(r.StageLaps[visit]-1, 0)
+		}
+		for len(laps) <= lap {
+			laps = append(laps, nil)
+		}
+		laps[lap] = append(laps[lap], visit)
+	}
+	return laps
+}
+
+// stageAt returns the visit shot r-th on the lap, or -1 if there is none.
+func stageAt(stages [][]int, lap, r int) int {
+	if lap >= len(stages) || r >= len(stages[lap]) {
+		return -1
+	}
+	return stages[lap][r]
+}
+
+// flattenLegs puts the leg rows of a relay team right after the team row.
+func flattenLegs(rows []ReportRow) []ReportRow {
+	var flat []ReportRow
+	for _, row := range rows {
+		flat = append(flat, row)
+		flat = append(flat, row.Legs...)
+	}
+	return flat
+}
+
+func csvRowFields(r ReportRow) []string {
+	fields := []string{strconv.Itoa(r.CompetitorID), "", r.Status, "", "", "",
+		formatClock(r.ScheduledStart), formatClock(r.ActualStart),
+		r.Entry.Bib, r.Entry.Name, r.Entry.Nation, r.Entry.Team, r.Entry.Category}
+	if r.Leg > 0 {
+		fields[1] = strconv.Itoa(r.Leg)
+	}
+	if r.ranked() {
+		fields[3] = strconv.Itoa(r.Rank)
+		fields[4] = FormatDuration(r.TotalTime)
+		fields[5] = FormatDuration(r.Behind)
+	}
+	return fields
+}
+
+// csvLapHeader names the columns csvLap fills.
+func csvLapHeader(prefix string) []string {
+	return []string{prefix + "time", prefix + "speed",
+		prefix + "course", prefix + "ski_speed", prefix + "range", prefix + "penalty"}
+}
+
+func csvLap(r ReportRow, lap int) []string {
+	if lap >= len(r.LapTimes) {
+		return make([]string, len(csvLapHeader("")))
+	}
+	fields := []string{FormatDuration(r.LapTimes[lap]), strconv.FormatFloat(r.LapSpeeds[lap], 'f', 3, 64)}
+	if lap >= len(r.LapCourseTimes) {
+		// rows read back from a text report have no breakdown
+		return append(fields, "", "", "", "")
+	}
+	return append(fields,
+		FormatDuration(r.LapCourseTimes[lap]), strconv.FormatFloat(r.LapSkiSpeeds[lap], 'f', 3, 64),
+		FormatDuration(r.LapRangeTimes[lap]), FormatDuration(r.LapPenaltyTimes[lap]))
+}
+
+func csvTotals(r ReportRow) []string {
+	speed := strconv.FormatFloat(r.PenaltySpeed, 'f', 3, 64)
+	if r.TimedPenalty {
+		speed = ""
+	}
+	fields := []string{FormatDuration(r.PenaltyTime), speed, FormatDuration(r.TimePenalty),
+		strconv.Itoa(r.Hits), strconv.Itoa(r.Shots), "", "", "", ""}
+	for _, ps := range r.Positions {
+		switch ps.Position {
+		case config.PositionProne:
+			fields[5], fields[6] = strconv.Itoa(ps.Hits), strconv.Itoa(ps.Shots)
+		case config.PositionStanding:
+			fields[7], fields[8] = strconv.Itoa(ps.Hits), strconv.Itoa(ps.Shots)
+		}
+	}
+	return fields
+}
+
+// csvStageHeader names the columns csvStage fills.
+func csvStageHeader(prefix string) []string {
+	return []string{prefix, prefix + "_position", prefix + "_time", prefix + "_penalty_loops", prefix + "_penalty_time"}
+}
+
+// csvStage returns the shooting pattern, the position and the range time of
+// the visit with the penalty loops that followed it.
+func csvStage(r ReportRow, visit int) []string {
+	fields := make([]string, len(csvStageHeader("")))
+	if visit < 0 || visit >= len(r.Shooting) {
+		return fields
+	}
+	fields[0] = r.Shooting[visit]
+	if visit < len(r.StagePositions) {
+		fields[1] = string(r.StagePositions[visit])
+	}
+	if visit < len(r.StageRangeTimes) {
+		fields[2] = FormatDuration(r.StageRangeTimes[visit])
+	}
+	for _, ps := range r.PenaltyStages {
+		if ps.Stage == visit+1 {
+			fields[3], fields[4] = strconv.Itoa(ps.Owed), FormatDuration(ps.Time)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,184 @@
+package engine
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/events"
+)
+
+// JSONSchemaVersion is bumped on every incompatible change of the JSON report.
+const JSONSchemaVersion = 1
+
+type jsonReport struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	Rows          []jsonRow `json:"rows"`
+}
+
+type jsonDuration struct {
+	Ms        int64  `json:"ms"`
+	Formatted string `json:"formatted"`
+}
+
+type jsonLap struct {
+	Time        jsonDuration `json:"time"`
+	Speed       float64      `json:"speed"`
+	CourseTime  jsonDuration `json:"courseTime"`
+	RangeTime   jsonDuration `json:"rangeTime"`
+	PenaltyTime jsonDuration `json:"penaltyTime"`
+	SkiSpeed    float64      `json:"skiSpeed"`
+}
+
+type jsonPenalty struct {
+	Time  jsonDuration `json:"time"`
+	Speed *float64     `json:"speed"` // null when the penalty is added time
+}
+
+type jsonPenaltyStage struct {
+	Stage   int          `json:"stage"`
+	Range   int          `json:"range"`
+	Lap     int          `json:"lap"`
+	Owed    int          `json:"owed"`
+	Skipped int          `json:"skipped"`
+	Time    jsonDuration `json:"time"`
+	Speed   float64      `json:"speed"`
+}
+
+type jsonPosition struct {
+	Position string `json:"position"`
+	Stages   int    `json:"stages"`
+	Hits     int    `json:"hits"`
+	Shots    int    `json:"shots"`
+}
+
+type jsonRow struct {
+	CompetitorID   int                `json:"competitorId"`
+	Leg            int                `json:"leg,omitempty"`
+	Athlete        int                `json:"athlete,omitempty"`
+	Bib            string             `json:"bib,omitempty"`
+	Name           string             `json:"name,omitempty"`
+	Nation         string             `json:"nation,omitempty"`
+	Team           string             `json:"team,omitempty"`
+	Category       string             `json:"category,omitempty"`
+	Status         string             `json:"status"`
+	Rank           int                `json:"rank,omitempty"`
+	TotalTime      *jsonDuration      `json:"totalTime,omitempty"`
+	Behind         *jsonDuration      `json:"behind,omitempty"`
+	ScheduledStart string             `json:"scheduledStart,omitempty"`
+	ActualStart    string             `json:"actualStart,omitempty"`
+	Laps           []jsonLap          `json:"laps"`
+	Penalty        jsonPenalty        `json:"penalty"`
+	PenaltyStages  []jsonPenaltyStage `json:"penaltyStages"`
+	TimePenalty    jsonDuration       `json:"timePenalty"`
+	Hits           int                `json:"hits"`
+	Shots          int                `json:"shots"`
+	Shooting       []string           `json:"shooting"`
+	RangeTimes     []jsonDuration     `json:"rangeTimes"`
+	StagePositions []string           `json:"stagePositions,omitempty"`
+	Positions      []jsonPosition     `json:"positions,omitempty"`
+	Qualified      bool               `json:"qualified,omitempty"`
+	Violations     []string           `json:"violations,omitempty"`
+	Legs           []jsonRow          `json:"legs,omitempty"`
+}
+
+// WriteJSON writes the report rows as a versioned JSON document.
+func WriteJSON(w io.Writer, rows []ReportRow) error {
+	report := jsonReport{
+		SchemaVersion: JSONSchemaVersion,
+		Rows:          make([]jsonRow, 0, len(rows)),
+	}
+	for _, row := range rows {
+		report.Rows = append(report.Rows, newJSONRow(row))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+func newJSONRow(r ReportRow) jsonRow {
+	row := jsonRow{
+		CompetitorID:   r.CompetitorID,
+		Leg:            r.Leg,
+		Athlete:        r.Athlete,
+		Bib:            r.Entry.Bib,
+		Name:           r.Entry.Name,
+		Nation:         r.Entry.Nation,
+		Team:           r.Entry.Team,
+		Category:       r.Entry.Category,
+		Status:         r.Status,
+		ScheduledStart: formatClock(r.ScheduledStart),
+		ActualStart:    formatClock(r.ActualStart),
+		Laps:           make([]jsonLap, 0, len(r.LapTimes)),
+		Penalty:        jsonPenalty{Time: newJSONDuration(r.PenaltyTime)},
+		TimePenalty:    newJSONDuration(r.TimePenalty),
+		Hits:           r.Hits,
+		Shots:          r.Shots,
+		Shooting:       r.Shooting,
+		Qualified:      r.Qualified,
+		Violations:     r.Violations,
+	}
+	if row.Shooting == nil {
+		row.Shooting = []string{}
+	}
+	row.PenaltyStages = make([]jsonPenaltyStage, 0, len(r.PenaltyStages))
+	for _, ps := range r.PenaltyStages {
+		row.PenaltyStages = append(row.PenaltyStages, jsonPenaltyStage{
+			Stage:   ps.Stage,
+			Range:   ps.Range,
+			Lap:     ps.Lap,
+			Owed:    ps.Owed,
+			Skipped: ps.Skipped,
+			Time:    newJSONDuration(ps.Time),
+			Speed:   ps.Speed,
+		})
+	}
+	row.RangeTimes = make([]jsonDuration, 0, len(r.StageRangeTimes))
+	for _, rangeTime := range r.StageRangeTimes {
+		row.RangeTimes = append(row.RangeTimes, newJSONDuration(rangeTime))
+	}
+	for _, position := range r.StagePositions {
+		row.StagePositions = append(row.StagePositions, string(position))
+	}
+	for _, ps := range r.Positions {
+		row.Positions = append(row.Positions, jsonPosition{
+			Position: string(ps.Position),
+			Stages:   ps.Stages,
+			Hits:     ps.Hits,
+			Shots:    ps.Shots,
+		})
+	}
+	if r.ranked() {
+		total, behind := newJSONDuration(r.TotalTime), newJSONDuration(r.Behind)
+		row.Rank, row.TotalTime, row.Behind = r.Rank, &total, &behind
+	}
+	for i, lapTime := range r.LapTimes {
+		lap := jsonLap{Time: newJSONDuration(lapTime), Speed: r.LapSpeeds[i]}
+		if i < len(r.LapCourseTimes) {
+			lap.CourseTime = newJSONDuration(r.LapCourseTimes[i])
+			lap.RangeTime = newJSONDuration(r.LapRangeTimes[i])
+			lap.PenaltyTime = newJSONDuration(r.LapPenaltyTimes[i])
+			lap.SkiSpeed = r.LapSkiSpeeds[i]
+		}
+		row.Laps = append(row.Laps, lap)
+	}
+	if !r.TimedPenalty {
+		speed := r.PenaltySpeed
+		row.Penalty.Speed = &speed
+	}
+	for _, leg := range r.Legs {
+		row.Legs = append(row.Legs, newJSONRow(leg))
+	}
+	return row
+}
+
+func newJSONDuration(d time.Duration) jsonDuration {
+	return jsonDuration{Ms: d.Milliseconds(), Formatted: FormatDuration(d)}
+}
+
+func formatClock(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(events.TimeLayoutHMSMilli)
+}
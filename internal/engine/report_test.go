@@ -1,11 +1,20 @@
 package engine
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/config"
+	"github.com/zahartd/biathlon_competitions_system/internal/models"
 )
 
 func TestFormatDuration(t *testing.T) {
@@ -22,8 +31,8 @@ func TestFormatDuration(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			actual := formatDuration(tc.d)
-			assert.Equal(t, actual, tc.expected, fmt.Sprintf("formatDuration(%v) = %q, but expected %q", tc.d, actual, tc.expected))
+			actual := FormatDuration(tc.d)
+			assert.Equal(t, actual, tc.expected, fmt.Sprintf("FormatDuration(%v) = %q, but expected %q", tc.d, actual, tc.expected))
 		})
 	}
 }
@@ -61,3 +70,335 @@ func TestReportRow_Format(t *testing.T) {
 		})
 	}
 }
+
+func TestSortByResult(t *testing.T) {
+	rows := []ReportRow{
+		{CompetitorID: 1, Status: StatusNotStarted},
+		{CompetitorID: 2, Status: StatusFinished, TotalTime: 30 * time.Minute},
+		{CompetitorID: 3, Status: StatusNotFinished},
+		{CompetitorID: 4, Status: StatusFinished, TotalTime: 28 * time.Minute},
+		{CompetitorID: 5, Status: StatusFinished, TotalTime: 30 * time.Minute},
+		{CompetitorID: 6, Status: StatusFinished, TotalTime: 31 * time.Minute},
+	}
+
+	rankRows(rows)
+	SortByResult(rows)
+
+	var ids, ranks []int
+	var behind []time.Duration
+	for _, r := range rows {
+		ids = append(ids, r.CompetitorID)
+		ranks = append(ranks, r.Rank)
+		behind = append(behind, r.Behind)
+	}
+	assert.Equal(t, []int{4, 2, 5, 6, 3, 1}, ids)
+	assert.Equal(t, []int{1, 2, 2, 4, 0, 0}, ranks)
+	assert.Equal(t, []time.Duration{0, 2 * time.Minute, 2 * time.Minute, 3 * time.Minute, 0, 0}, behind)
+}
+
+func TestParseReport(t *testing.T) {
+	report := "[Finished] 1 [{12:35.380, 4.633}, {12:50.667, 4.542}] {02:30.000, 3.000} 7/10\n" +
+		"[NotFinished] 2 [{29:03.872, 2.094}] {01:52.476, 0.445} 4/5\n" +
+		"[Finished] 3 [{12:39.746, 4.607}, {12:38.610, 4.614}] {01:40.000, 3.000} 8/10\n" +
+		"[NotStarted] 4 [] {00:00.000, 0.000} 0/0\n" +
+		"[Finished] 5 [{30:00.000, 2.000}] {03:00.000, } 2/5\n"
+
+	rows, err := ParseReport(strings.NewReader(report))
+	require.NoError(t, err)
+	require.Len(t, rows, 5)
+
+	var formatted strings.Builder
+	for _, r := range rows {
+		formatted.WriteString(r.Format())
+	}
+	assert.Equal(t, report, formatted.String())
+	assert.Equal(t, 25*time.Minute+26047*time.Millisecond, rows[0].TotalTime)
+	assert.Equal(t, 2, rows[0].Rank)
+	assert.Equal(t, 1, rows[2].Rank)
+	assert.True(t, rows[4].TimedPenalty)
+	assert.Equal(t, 33*time.Minute, rows[4].TotalTime, "added time counts towards the result")
+
+	SortByResult(rows)
+	var result strings.Builder
+	for _, r := range rows {
+		result.WriteString(r.FormatResult())
+	}
+	reparsed, err := ParseReport(strings.NewReader(result.String()))
+	require.NoError(t, err)
+	assert.Equal(t, rows, reparsed)
+
+	_, err = ParseReport(strings.NewReader("garbage\n"))
+	assert.ErrorContains(t, err, "invalid report line")
+}
+
+func TestPursuitStartList(t *testing.T) {
+	start := time.Date(0, time.January, 1, 10, 0, 0, 0, time.UTC)
+	rows := []ReportRow{
+		{CompetitorID: 1, Status: StatusFinished, TotalTime: 25 * time.Minute},
+		{CompetitorID: 2, Status: StatusNotFinished},
+		{CompetitorID: 3, Status: StatusFinished, TotalTime: 24*time.Minute + 30*time.Second},
+	}
+	rankRows(rows)
+
+	assert.Equal(t, map[int]time.Time{
+		1: start.Add(30 * time.Second),
+		3: start,
+	}, PursuitStartList(rows, start))
+}
+
+func TestPursuitStartListFromTimedPenalties(t *testing.T) {
+	start := time.Date(0, time.January, 1, 10, 0, 0, 0, time.UTC)
+	report := "[Finished] 1 [{20:00.000, 4.000}] {01:00.000, } 9/10\n" +
+		"[Finished] 2 [{20:00.000, 4.000}] {03:00.000, } 7/10\n"
+	rows, err := ParseReport(strings.NewReader(report))
+	require.NoError(t, err)
+
+	assert.Equal(t, map[int]time.Time{
+		1: start,
+		2: start.Add(2 * time.Minute),
+	}, PursuitStartList(rows, start), "handicaps include the time added per miss")
+}
+
+func TestStartListFor(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sprint.out")
+	report := "[Finished] 1 [{12:35.380, 4.633}, {12:50.667, 4.542}] {02:30.000, 3.000} 7/10\n" +
+		"[Finished] 3 [{12:39.746, 4.607}, {12:38.610, 4.614}] {01:40.000, 3.000} 8/10\n"
+	require.NoError(t, os.WriteFile(path, []byte(report), 0o644))
+	start := time.Date(0, time.January, 1, 10, 0, 0, 0, time.UTC)
+
+	starts, err := StartListFor(config.Config{RaceType: config.RacePursuit, Start: start}, path)
+	require.NoError(t, err)
+	assert.Equal(t, map[int]time.Time{
+		1: start.Add(7691 * time.Millisecond),
+		3: start,
+	}, starts)
+
+	starts, err = StartListFor(config.Config{RaceType: config.RaceSuperSprintFinal, Start: start, Finalists: 1}, path)
+	require.NoError(t, err)
+	assert.Equal(t, map[int]time.Time{3: start}, starts)
+
+	starts, err = StartListFor(config.Config{RaceType: config.RaceInterval, Start: start}, "")
+	require.NoError(t, err)
+	assert.Nil(t, starts, "an interval race starts from draws")
+
+	_, err = StartListFor(config.Config{RaceType: config.RacePursuit, Start: start}, filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestWriteJSON(t *testing.T) {
+	rows := []ReportRow{
+		{
+			CompetitorID:   1,
+			Status:         StatusFinished,
+			Rank:           1,
+			TotalTime:      22 * time.Second,
+			LapTimes:       []time.Duration{10 * time.Second, 12 * time.Second},
+			LapSpeeds:      []float64{100, 1000.0 / 12},
+			PenaltyTime:    time.Minute,
+			TimedPenalty:   true,
+			Hits:           4,
+			Shots:          5,
+			Shooting:       []string{"XX.XX"},
+			StagePositions: []config.Position{config.PositionProne},
+			Positions:      []PositionShooting{{Position: config.PositionProne, Stages: 1, Hits: 4, Shots: 5}},
+			ScheduledStart: time.Date(0, time.January, 1, 10, 0, 0, 0, time.UTC),
+			ActualStart:    time.Date(0, time.January, 1, 10, 0, 1, 500*1e6, time.UTC),
+		},
+		{CompetitorID: 2, Status: StatusNotStarted},
+	}
+
+	var b strings.Builder
+	require.NoError(t, WriteJSON(&b, rows))
+
+	var report struct {
+		SchemaVersion int `json:"schemaVersion"`
+		Rows          []struct {
+			CompetitorID   int    `json:"competitorId"`
+			Status         string `json:"status"`
+			Rank           int    `json:"rank"`
+			ScheduledStart string `json:"scheduledStart"`
+			ActualStart    string `json:"actualStart"`
+			TotalTime      *struct {
+				Ms        int64  `json:"ms"`
+				Formatted string `json:"formatted"`
+			} `json:"totalTime"`
+			Laps []struct {
+				Time struct {
+					Ms int64 `json:"ms"`
+				} `json:"time"`
+				Speed float64 `json:"speed"`
+			} `json:"laps"`
+			Penalty struct {
+				Speed *float64 `json:"speed"`
+			} `json:"penalty"`
+			Hits           int      `json:"hits"`
+			Shots          int      `json:"shots"`
+			Shooting       []string `json:"shooting"`
+			StagePositions []string `json:"stagePositions"`
+			Positions      []struct {
+				Position string `json:"position"`
+				Hits     int    `json:"hits"`
+				Shots    int    `json:"shots"`
+			} `json:"positions"`
+		} `json:"rows"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(b.String()), &report))
+
+	assert.Equal(t, JSONSchemaVersion, report.SchemaVersion)
+	require.Len(t, report.Rows, 2)
+
+	first := report.Rows[0]
+	assert.Equal(t, 1, first.Rank)
+	assert.Equal(t, "10:00:00.000", first.ScheduledStart)
+	assert.Equal(t, "10:00:01.500", first.ActualStart)
+	require.NotNil(t, first.TotalTime)
+	assert.Equal(t, int64(22000), first.TotalTime.Ms)
+	assert.Equal(t, "00:22.000", first.TotalTime.Formatted)
+	require.Len(t, first.Laps, 2)
+	assert.Equal(t, int64(10000), first.Laps[0].Time.Ms)
+	assert.Equal(t, 100.0, first.Laps[0].Speed)
+	assert.Nil(t, first.Penalty.Speed)
+	assert.Equal(t, []string{"XX.XX"}, first.Shooting)
+	assert.Equal(t, []string{"prone"}, first.StagePositions)
+	require.Len(t, first.Positions, 1)
+	assert.Equal(t, "prone", first.Positions[0].Position)
+	assert.Equal(t, 4, first.Positions[0].Hits)
+
+	second := report.Rows[1]
+	assert.Equal(t, StatusNotStarted, second.Status)
+	assert.Zero(t, second.Rank)
+	assert.Nil(t, second.TotalTime)
+	assert.Empty(t, second.ActualStart)
+	assert.Empty(t, second.Laps)
+	assert.Empty(t, second.Positions)
+}
+
+func TestWriteCSV(t *testing.T) {
+	cfg := config.Config{Laps: 2, FiringLines: 1}
+	rows := []ReportRow{
+		{
+			CompetitorID: 1,
+			Status:       StatusFinished,
+			Rank:         1,
+			TotalTime:    22 * time.Second,
+			LapTimes:     []time.Duration{10 * time.Second, 12 * time.Second},
+			LapSpeeds:    []float64{100, 1000.0 / 12},
+			PenaltyTime:  5 * time.Second,
+			PenaltySpeed: 30,
+			Hits:         9,
+			Shots:        10,
+			Shooting:     []string{"XX.XX", "XXXXX"},
+			StageLaps:    []int{1, 2},
+			Entry:        models.RosterEntry{CompetitorID: 1, Bib: "12", Name: "J. Doe", Nation: "NOR"},
+
+			LapCourseTimes:  []time.Duration{8 * time.Second, 10 * time.Second},
+			LapRangeTimes:   []time.Duration{time.Second, 2 * time.Second},
+			LapPenaltyTimes: []time.Duration{time.Second, 0},
+			LapSkiSpeeds:    []float64{125, 100},
+			StageRangeTimes: []time.Duration{time.Second, 2 * time.Second},
+			PenaltyStages:   []PenaltyStage{{Stage: 1, Range: 1, Lap: 1, Owed: 1, Time: time.Second, Speed: 100}},
+			StagePositions:  []config.Position{config.PositionProne, config.PositionStanding},
+			Positions: []PositionShooting{
+				{Position: config.PositionProne, Stages: 1, Hits: 4, Shots: 5},
+				{Position: config.PositionStanding, Stages: 1, Hits: 5, Shots: 5},
+			},
+		},
+		{CompetitorID: 2, Status: StatusNotStarted},
+	}
+
+	t.Run("wide", func(t *testing.T) {
+		var b strings.Builder
+		require.NoError(t, WriteCSV(&b, rows, cfg, CSVWide))
+		assert.Equal(t, ""+
+			"competitor,leg,status,rank,total_time,behind,scheduled_start,actual_start,bib,name,nation,team,category,"+
+			"lap1_time,lap1_speed,lap1_course,lap1_ski_speed,lap1_range,lap1_penalty,"+
+			"lap2_time,lap2_speed,lap2_course,lap2_ski_speed,lap2_range,lap2_penalty,"+
+			"penalty_time,penalty_speed,time_penalty,hits,shots,prone_hits,prone_shots,standing_hits,standing_shots,"+
+			"range1,range1_position,range1_time,range1_penalty_loops,range1_penalty_time,"+
+			"range2,range2_position,range2_time,range2_penalty_loops,range2_penalty_time\n"+
+			"1,,Finished,1,00:22.000,00:00.000,,,12,J. Doe,NOR,,,"+
+			"00:10.000,100.000,00:08.000,125.000,00:01.000,00:01.000,"+
+			"00:12.000,83.333,00:10.000,100.000,00:02.000,00:00.000,"+
+			"00:05.000,30.000,00:00.000,9,10,4,5,5,5,"+
+			"XX.XX,prone,00:01.000,1,00:01.000,XXXXX,standing,00:02.000,,\n"+
+			"2,,NotStarted,,,,,,,,,,,,,,,,,,,,,,,00:00.000,0.000,00:00.000,0,0,,,,,,,,,,,,,,\n",
+			b.String())
+	})
+
+	t.Run("long", func(t *testing.T) {
+		var b strings.Builder
+		require.NoError(t, WriteCSV(&b, rows, cfg, CSVLong))
+		assert.Equal(t, ""+
+			"competitor,leg,status,rank,total_time,behind,scheduled_start,actual_start,bib,name,nation,team,category,"+
+			"lap,lap_time,lap_speed,lap_course,lap_ski_speed,lap_range,lap_penalty,"+
+			"range1,range1_position,range1_time,range1_penalty_loops,range1_penalty_time\n"+
+			"1,,Finished,1,00:22.000,00:00.000,,,12,J. Doe,NOR,,,1,00:10.000,100.000,00:08.000,125.000,00:01.000,00:01.000,XX.XX,prone,00:01.000,1,00:01.000\n"+
+			"1,,Finished,1,00:22.000,00:00.000,,,12,J. Doe,NOR,,,2,00:12.000,83.333,00:10.000,100.000,00:02.000,00:00.000,XXXXX,standing,00:02.000,,\n"+
+			"2,,NotStarted,,,,,,,,,,,,,,,,,,,,,,\n",
+			b.String())
+	})
+
+	t.Run("stages follow their lap", func(t *testing.T) {
+		// lap 1 has no visit, lap 2 one too many
+		rows := []ReportRow{{
+			CompetitorID: 1,
+			Status:       StatusFinished,
+			LapTimes:     []time.Duration{10 * time.Second, 12 * time.Second},
+			LapSpeeds:    []float64{100, 1000.0 / 12},
+			Shooting:     []string{"XX.XX", "XXXXX"},
+			StageLaps:    []int{2, 2},
+		}}
+		var wide strings.Builder
+		require.NoError(t, WriteCSV(&wide, rows, cfg, CSVWide))
+		assert.Contains(t, wide.String(), ",range4,range4_position,")
+		assert.True(t, strings.HasSuffix(wide.String(), ",,,,,,,,,,XX.XX,,,,,XXXXX,,,,\n"), wide.String())
+
+		var long strings.Builder
+		require.NoError(t, WriteCSV(&long, rows, cfg, CSVLong))
+		lines := strings.Split(strings.TrimSpace(long.String()), "\n")
+		require.Len(t, lines, 3)
+		assert.True(t, strings.HasSuffix(lines[1], ",,,,,,,,,,"), lines[1])
+		assert.True(t, strings.HasSuffix(lines[2], ",XX.XX,,,,,XXXXX,,,,"), lines[2])
+	})
+
+	t.Run("unknown layout", func(t *testing.T) {
+		assert.Error(t, WriteCSV(io.Discard, rows, cfg, "tall"))
+	})
+}
+
+func TestRowFilterAndGroups(t *testing.T) {
+	rows := []ReportRow{
+		{CompetitorID: 1, Rank: 2, Entry: models.RosterEntry{Nation: "SWE", Category: "Men"}},
+		{CompetitorID: 2, Rank: 1, Entry: models.RosterEntry{Nation: "NOR", Category: "Women"}},
+		{CompetitorID: 3},
+		{CompetitorID: 4, Rank: 3, Entry: models.RosterEntry{Nation: "NOR", Category: "Men"}},
+	}
+	ids := func(rows []ReportRow) []int {
+		var ids []int
+		for _, r := range rows {
+			ids = append(ids, r.CompetitorID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []int{2, 4}, ids(RowFilter{Nation: "nor"}.Apply(rows)))
+	assert.Equal(t, []int{4}, ids(RowFilter{Nation: "NOR", Category: "Men"}.Apply(rows)))
+	assert.Equal(t, []int{1, 2, 3, 4}, ids(RowFilter{}.Apply(rows)))
+	assert.Equal(t, 3, RowFilter{Category: "Men"}.Apply(rows)[1].Rank, "places are kept")
+
+	groups, err := GroupRows(rows, GroupByNation)
+	require.NoError(t, err)
+	require.Len(t, groups, 3)
+	assert.Equal(t, "NOR", groups[0].Key)
+	assert.Equal(t, []int{2, 4}, ids(groups[0].Rows))
+	assert.Equal(t, "SWE", groups[1].Key)
+	assert.Equal(t, "", groups[2].Key, "rows without a nation come last")
+	assert.Equal(t, []int{3}, ids(groups[2].Rows))
+
+	groups, err = GroupRows(rows, GroupByCategory)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Men", "Women", ""}, []string{groups[0].Key, groups[1].Key, groups[2].Key})
+
+	_, err = GroupRows(rows, "team")
+	assert.Error(t, err)
+}
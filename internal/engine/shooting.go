@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"sort"
+	"time"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/config"
+)
+
+// ShootingStage is a firing range visit the competitor has completed.
+type ShootingStage struct {
+	CompetitorID int
+	Stage        int             // visit of the competitor, starting at 1
+	Range        int             // firing range number
+	Lap          int             // main lap of the visit, starting at 1
+	Position     config.Position // empty without a configured sequence
+	Enter        time.Time
+	Leave        time.Time
+	Targets      [targetsPerRange]bool // knocked down targets, indexed by target number - 1
+	HitTimes     []time.Time
+	Hits         int
+	Shots        int
+}
+
+// ShootingStages returns the completed firing range visits of all
+// competitors, in start list order.
+func (e *Engine) ShootingStages() []ShootingStage {
+	var stages []ShootingStage
+	for _, st := range e.sortedStates() {
+		for i := range st.RangeVisits {
+			visit := &st.RangeVisits[i]
+			if visit.Leave.IsZero() {
+				continue
+			}
+			stages = append(stages, ShootingStage{
+				CompetitorID: st.CompetitorID,
+				Stage:        i + 1,
+				Range:        visit.Range,
+				Lap:          visit.Lap + 1,
+				Position:     visit.Position,
+				Enter:        visit.Enter,
+				Leave:        visit.Leave,
+				Targets:      visit.Targets,
+				HitTimes:     visit.HitTimes,
+				Hits:         visit.hits(),
+				Shots:        visit.shots(),
+			})
+		}
+	}
+	return stages
+}
+
+// stagePosition returns the shooting position of the range visit the
+// competitor starts. The configured sequence restarts with every relay leg
+// and repeats if the competitor shoots more stages than it lists.
+func (e *Engine) stagePosition(state *competitorState) config.Position {
+	if len(e.cfg.Positions) == 0 {
+		return ""
+	}
+	stage := 0
+	legStart := e.legFirstLap(state.Leg)
+	for _, visit := range state.RangeVisits {
+		if visit.Lap >= legStart {
+			stage++
+		}
+	}
+	return e.cfg.Positions[stage%len(e.cfg.Positions)]
+}
+
+// addPositionShooting counts the visit into the row's shooting in its
+// position, keeping prone before standing.
+func (r *ReportRow) addPositionShooting(visit *rangeVisit) {
+	for i := range r.Positions {
+		if r.Positions[i].Position == visit.Position {
+			r.Positions[i].add(visit)
+			return
+		}
+	}
+	ps := PositionShooting{Position: visit.Position}
+	ps.add(visit)
+	r.Positions = append(r.Positions, ps)
+	sort.SliceStable(r.Positions, func(i, j int) bool {
+		return r.Positions[i].Position == config.PositionProne && r.Positions[j].Position != config.PositionProne
+	})
+}
+
+func (ps *PositionShooting) add(visit *rangeVisit) {
+	ps.Stages++
+	ps.Hits += visit.hits()
+	ps.Shots += visit.shots()
+}
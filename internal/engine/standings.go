@@ -0,0 +1,194 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Standing is a place in the intermediate standings.
+type Standing struct {
+	Rank         int
+	CompetitorID int
+	Laps         int           // main laps completed
+	Stages       int           // shooting stages completed
+	Time         time.Duration // race time at the last checkpoint passed
+	Behind       time.Duration // gap to the best time at the same checkpoint
+	Finished     bool
+}
+
+// Format renders the standing as a result list line.
+func (s Standing) Format() string {
+	status := ""
+	if s.Finished {
+		status = "[Finished] "
+	}
+	return fmt.Sprintf("%d. %s%d %s +%s (laps %d, stages %d)\n",
+		s.Rank, status, s.CompetitorID, FormatDuration(s.Time), FormatDuration(s.Behind), s.Laps, s.Stages)
+}
+
+// Checkpoint is a point of the course: the end of main lap Lap or the exit
+// from shooting stage Stage. Exactly one of them is set.
+type Checkpoint struct {
+	Lap   int
+	Stage int
+}
+
+// ParseCheckpoint reads a checkpoint written as lap:N or stage:K.
+func ParseCheckpoint(str string) (Checkpoint, error) {
+	kind, num, ok := strings.Cut(str, ":")
+	n, err := strconv.Atoi(num)
+	if !ok || err != nil || n < 1 {
+		return Checkpoint{}, fmt.Errorf("invalid checkpoint %q, expected lap:N or stage:K", str)
+	}
+	switch kind {
+	case "lap":
+		return Checkpoint{Lap: n}, nil
+	case "stage":
+		return Checkpoint{Stage: n}, nil
+	}
+	return Checkpoint{}, fmt.Errorf("invalid checkpoint %q, expected lap:N or stage:K", str)
+}
+
+// checkpointPass is a checkpoint passed by a competitor together with the
+// laps and stages completed by then.
+type checkpointPass struct {
+	Lap    bool // lap end, otherwise range exit
+	Laps   int
+	Stages int
+	At     time.Time
+}
+
+// passes returns the checkpoints passed by the competitor in time order.
+func (st *competitorState) passes() []checkpointPass {
+	var passes []checkpointPass
+	for _, at := range st.LapEndTimes {
+		passes = append(passes, checkpointPass{Lap: true, At: at})
+	}
+	for _, visit := range st.RangeVisits {
+		if !visit.Leave.IsZero() {
+			passes = append(passes, checkpointPass{At: visit.Leave})
+		}
+	}
+	sort.SliceStable(passes, func(i, j int) bool {
+		return passes[i].At.Before(passes[j].At)
+	})
+
+	laps, stages := 0, 0
+	for i := range passes {
+		if passes[i].Lap {
+			laps++
+		} else {
+			stages++
+		}
+		passes[i].Laps, passes[i].Stages = laps, stages
+	}
+	return passes
+}
+
+// Standings returns the standings after the latest event.
+func (e *Engine) Standings() []Standing {
+	return e.standingsAt(func(time.Time) bool { return true })
+}
+
+// StandingsAt returns the order at clock time t. Competitors who started by
+// then are ranked by the furthest checkpoint passed, then by race time at
+// it. Finishers come first with their result including time penalties.
+// Competitors who dropped out or were disqualified by then are left out.
+func (e *Engine) StandingsAt(t time.Time) []Standing {
+	return e.standingsAt(func(at time.Time) bool { return !at.After(t) })
+}
+
+func (e *Engine) standingsAt(reached func(time.Time) bool) []Standing {
+	var standings []Standing
+	for _, st := range e.states {
+		if st.ActualStart.IsZero() || !reached(st.ActualStart) {
+			continue
+		}
+		if (st.NotStarted || st.NotFinished) && reached(st.OutTime) {
+			continue
+		}
+		standing := Standing{CompetitorID: st.CompetitorID}
+		for _, pass := range st.passes() {
+			if !reached(pass.At) {
+				break
+			}
+			standing.Laps, standing.Stages = pass.Laps, pass.Stages
+			standing.Time = pass.At.Sub(e.raceStart(st))
+		}
+		if !st.FinishTime.IsZero() && reached(st.FinishTime) {
+			standing.Finished = true
+			standing.Time = st.FinishTime.Sub(e.raceStart(st)) + st.TimePenalty
+		}
+		standings = append(standings, standing)
+	}
+	rankStandings(standings)
+	return standings
+}
+
+// StandingsAfter returns the order at the checkpoint: competitors who passed
+// it ranked by race time there.
+func (e *Engine) StandingsAfter(cp Checkpoint) []Standing {
+	var standings []Standing
+	for _, st := range e.states {
+		if st.NotStarted {
+			continue
+		}
+		for _, pass := range st.passes() {
+			if pass.Lap && cp.Lap > 0 && pass.Laps == cp.Lap ||
+				!pass.Lap && cp.Stage > 0 && pass.Stages == cp.Stage {
+				standings = append(standings, Standing{
+					CompetitorID: st.CompetitorID,
+					Laps:         pass.Laps,
+					Stages:       pass.Stages,
+					Time:         pass.At.Sub(e.raceStart(st)),
+				})
+				break
+			}
+		}
+	}
+	rankStandings(standings)
+	return standings
+}
+
+// rankStandings orders standings by progress and time and fills in places
+// and gaps to the best time at the same checkpoint. Ties share a place.
+func rankStandings(standings []Standing) {
+	sort.Slice(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if a.Finished != b.Finished {
+			return a.Finished
+		}
+		if a.Laps != b.Laps {
+			return a.Laps > b.Laps
+		}
+		if a.Stages != b.Stages {
+			return a.Stages > b.Stages
+		}
+		if a.Time != b.Time {
+			return a.Time < b.Time
+		}
+		return a.CompetitorID < b.CompetitorID
+	})
+
+	leader := 0 // best time at the same checkpoint
+	for i := range standings {
+		standings[i].Rank = i + 1
+		s, l := standings[i], standings[leader]
+		if s.Finished != l.Finished || s.Laps != l.Laps || s.Stages != l.Stages {
+			leader = i
+		}
+		if i > 0 && sameStanding(s, standings[i-1]) {
+			standings[i].Rank = standings[i-1].Rank
+		}
+		standings[i].Behind = standings[i].Time - standings[leader].Time
+	}
+}
+
+// sameStanding reports whether two standings tie: the same checkpoint passed
+// at the same race time.
+func sameStanding(a, b Standing) bool {
+	return a.Finished == b.Finished && a.Laps == b.Laps && a.Stages == b.Stages && a.Time == b.Time
+}
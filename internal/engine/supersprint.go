@@ -0,0 +1,24 @@
+package engine
+
+import "time"
+
+// markQualified marks the best finalists of a super sprint qualification.
+// Competitors sharing the last qualifying place all qualify.
+func markQualified(rows []ReportRow, finalists int) {
+	for i := range rows {
+		rows[i].Qualified = rows[i].ranked() && rows[i].Rank <= finalists
+	}
+}
+
+// FinalStartList builds the super sprint final start list from the
+// qualification result: every qualified athlete starts together at start.
+func FinalStartList(rows []ReportRow, finalists int, start time.Time) map[int]time.Time {
+	markQualified(rows, finalists)
+	starts := make(map[int]time.Time)
+	for _, row := range rows {
+		if row.Qualified {
+			starts[row.CompetitorID] = start
+		}
+	}
+	return starts
+}
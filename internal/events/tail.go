@@ -0,0 +1,47 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// Tail calls handle for every line of r. At the end of the input it polls for
+// more every interval, like `tail -f`, until ctx is done. A last line without
+// a newline is held back until it is complete. With a zero interval the end
+// of the input is final, e.g. for a closed pipe: Tail handles the last line
+// and returns nil.
+func Tail(ctx context.Context, r io.Reader, interval time.Duration, handle func(line string) error) error {
+	reader := bufio.NewReader(r)
+	var partial strings.Builder
+	for {
+		chunk, err := reader.ReadString('\n')
+		partial.WriteString(chunk)
+		if err == nil {
+			line := strings.TrimRight(partial.String(), "\r\n")
+			partial.Reset()
+			if err := handle(line); err != nil {
+				return err
+			}
+			continue
+		}
+		if !errors.Is(err, io.EOF) {
+			return err
+		}
+		if interval <= 0 {
+			if partial.Len() == 0 {
+				return nil
+			}
+			return handle(partial.String())
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
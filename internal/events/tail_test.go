@@ -0,0 +1,66 @@
+package events
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events")
+	require.NoError(t, os.WriteFile(path, []byte("first\nsec"), 0o644))
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	lines := make(chan string, 4)
+	done := make(chan error, 1)
+	go func() {
+		done <- Tail(ctx, f, time.Millisecond, func(line string) error {
+			lines <- line
+			return nil
+		})
+	}()
+
+	next := func() string {
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(time.Second):
+			t.Fatal("no line tailed")
+			return ""
+		}
+	}
+
+	assert.Equal(t, "first", next())
+
+	w, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	defer w.Close()
+	_, err = w.WriteString("ond\r\nthird\n")
+	require.NoError(t, err)
+
+	assert.Equal(t, "second", next())
+	assert.Equal(t, "third", next())
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestTailToEnd(t *testing.T) {
+	var lines []string
+	err := Tail(context.Background(), strings.NewReader("first\nsecond"), 0, func(line string) error {
+		lines = append(lines, line)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "second"}, lines)
+}
@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 type EventID int
 
@@ -17,10 +20,14 @@ const (
 	EventPenaltyLeave                    // The competitor left the penalty laps
 	EventLapEnd                          // The competitor ended the main lap
 	EventNotContinue                     // The competitor can`t continue
+	EventExchange                        // The relay team tagged the next leg
+	EventSpareRound                      // The relay athlete loaded a spare round
 
 	// Outgoing events
 	EventDisqualification = 32 // The competitor is disqualified
 	EventFinished         = 33 // The competitor has finished
+	EventLoopsSkipped     = 34 // The competitor skipped penalty laps
+	EventLapped           = 35 // The competitor was lapped and pulled out
 )
 
 type Event struct {
@@ -29,3 +36,30 @@ type Event struct {
 	CompetitorID int
 	ExtraParams  []string
 }
+
+var eventNames = map[EventID]string{
+	EventRegister:         "register",
+	EventDraw:             "draw",
+	EventOnLine:           "on_line",
+	EventStart:            "start",
+	EventFiring:           "firing",
+	EventHit:              "hit",
+	EventLeaveFiring:      "leave_firing",
+	EventPenaltyEnter:     "penalty_enter",
+	EventPenaltyLeave:     "penalty_leave",
+	EventLapEnd:           "lap_end",
+	EventNotContinue:      "not_continue",
+	EventExchange:         "exchange",
+	EventSpareRound:       "spare_round",
+	EventDisqualification: "disqualification",
+	EventFinished:         "finished",
+	EventLoopsSkipped:     "loops_skipped",
+	EventLapped:           "lapped",
+}
+
+func (id EventID) String() string {
+	if name, ok := eventNames[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("EventID(%d)", int(id))
+}
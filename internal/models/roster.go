@@ -0,0 +1,40 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RosterEntry describes the athlete or the relay team behind a competitor ID.
+type RosterEntry struct {
+	CompetitorID int
+	Bib          string
+	Name         string
+	Nation       string
+	Team         string
+	Category     string
+}
+
+// Roster maps competitor IDs to their entries.
+type Roster map[int]RosterEntry
+
+// Summary renders the name and the nation of the entry, e.g. "J. Doe, NOR".
+func (e RosterEntry) Summary() string {
+	var details []string
+	for _, detail := range []string{e.Name, e.Nation} {
+		if detail != "" {
+			details = append(details, detail)
+		}
+	}
+	return strings.Join(details, ", ")
+}
+
+// Label renders the competitor for logs, e.g. "3 (J. Doe, NOR)". Only the ID
+// is left for a competitor missing from the roster.
+func (r Roster) Label(competitorID int) string {
+	summary := r[competitorID].Summary()
+	if summary == "" {
+		return fmt.Sprint(competitorID)
+	}
+	return fmt.Sprintf("%d (%s)", competitorID, summary)
+}
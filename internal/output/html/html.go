@@ -0,0 +1,155 @@
+// Package html renders the final race report as a standalone HTML page.
+// The page has no external assets, so it can be opened offline.
+package html
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/engine"
+	"github.com/zahartd/biathlon_competitions_system/internal/events"
+	"github.com/zahartd/biathlon_competitions_system/internal/models"
+	"github.com/zahartd/biathlon_competitions_system/internal/output"
+)
+
+//go:embed report.html.tmpl
+var reportTemplate string
+
+var tmpl = template.Must(template.New("report").Parse(reportTemplate))
+
+type page struct {
+	Title        string
+	Laps         []int
+	Results      []row
+	NotFinished  []row
+	NotStarted   []row
+	Disqualified []row
+	Legs         []row    // relay legs of every team, in team order
+	Log          []string // output log lines
+}
+
+type row struct {
+	Rank      string
+	RankKey   int
+	ID        int
+	Athlete   string // roster name and nation, e.g. "J. Doe, NOR"
+	Start     string
+	Total     string
+	TotalMs   int64
+	Behind    string
+	BehindMs  int64
+	Laps      []lap
+	Penalty   string
+	PenaltyMs int64
+	Hits      int
+	Shots     int
+	Shooting  []string
+	Positions []string // hits per shooting position, e.g. "prone 9/10"
+	Comment   string
+	Leg       int    // relay leg number of a leg row
+	LegOf     string // leg row: athlete of a single mixed relay team, e.g. "athlete 2"
+}
+
+type lap struct {
+	Time  string
+	Ms    int64
+	Speed string
+}
+
+// Render writes the page for the report rows followed by the event log in
+// output log wording. Competitors who never started are listed apart from
+// those disqualified for starting outside the window, relay legs get a table
+// of their own.
+func Render(w io.Writer, title string, rows []engine.ReportRow, log []models.Event) error {
+	p := page{Title: title}
+	laps := 0
+	roster := make(models.Roster)
+	for _, r := range rows {
+		laps = max(laps, len(r.LapTimes))
+		if r.Entry != (models.RosterEntry{}) {
+			roster[r.CompetitorID] = r.Entry
+		}
+		for _, leg := range r.Legs {
+			view := newRow(leg)
+			view.ID, view.Athlete, view.Leg = r.CompetitorID, r.Entry.Summary(), leg.Leg
+			if leg.Athlete > 0 {
+				view.LegOf = fmt.Sprintf("athlete %d", leg.Athlete)
+			}
+			p.Legs = append(p.Legs, view)
+		}
+		view := newRow(r)
+		switch {
+		case r.Disqualified:
+			p.Disqualified = append(p.Disqualified, view)
+		case r.Status == engine.StatusNotStarted:
+			p.NotStarted = append(p.NotStarted, view)
+		case r.Status == engine.StatusNotFinished:
+			view.Comment = r.NotFinishedMsg
+			p.NotFinished = append(p.NotFinished, view)
+		default:
+			p.Results = append(p.Results, view)
+		}
+	}
+	for i := range laps {
+		p.Laps = append(p.Laps, i+1)
+	}
+	for i := range p.Results {
+		for len(p.Results[i].Laps) < laps {
+			p.Results[i].Laps = append(p.Results[i].Laps, lap{})
+		}
+	}
+
+	var loggerOpts []output.LoggerOption
+	if len(roster) > 0 {
+		loggerOpts = append(loggerOpts, output.WithRoster(roster))
+	}
+	var b strings.Builder
+	logger := output.NewLogger(&b, loggerOpts...)
+	for _, event := range log {
+		if err := logger.Write(event); err != nil {
+			return err
+		}
+	}
+	if b.Len() > 0 {
+		p.Log = strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	}
+	return tmpl.Execute(w, p)
+}
+
+func newRow(r engine.ReportRow) row {
+	view := row{
+		Rank:      "-",
+		RankKey:   int(^uint(0) >> 1),
+		ID:        r.CompetitorID,
+		Athlete:   r.Entry.Summary(),
+		Penalty:   engine.FormatDuration(r.PenaltyTime),
+		PenaltyMs: r.PenaltyTime.Milliseconds(),
+		Hits:      r.Hits,
+		Shots:     r.Shots,
+		Shooting:  r.Shooting,
+	}
+	for _, ps := range r.Positions {
+		view.Positions = append(view.Positions, fmt.Sprintf("%s %d/%d", ps.Position, ps.Hits, ps.Shots))
+	}
+	if !r.ScheduledStart.IsZero() {
+		view.Start = r.ScheduledStart.Format(events.TimeLayoutHMSMilli)
+	}
+	if r.Rank > 0 {
+		view.Rank = strconv.Itoa(r.Rank)
+		view.RankKey = r.Rank
+		view.Total, view.TotalMs = engine.FormatDuration(r.TotalTime), r.TotalTime.Milliseconds()
+		view.Behind, view.BehindMs = "+"+engine.FormatDuration(r.Behind), r.Behind.Milliseconds()
+	}
+	for i, d := range r.LapTimes {
+		view.Laps = append(view.Laps, lap{
+			Time:  engine.FormatDuration(d),
+			Ms:    d.Milliseconds(),
+			Speed: strconv.FormatFloat(r.LapSpeeds[i], 'f', 3, 64),
+		})
+	}
+	return view
+}
@@ -0,0 +1,103 @@
+package html
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/config"
+	"github.com/zahartd/biathlon_competitions_system/internal/engine"
+	"github.com/zahartd/biathlon_competitions_system/internal/events"
+	"github.com/zahartd/biathlon_competitions_system/internal/models"
+)
+
+func TestRender(t *testing.T) {
+	rows := []engine.ReportRow{
+		{
+			CompetitorID: 1,
+			Status:       engine.StatusFinished,
+			Rank:         1,
+			TotalTime:    22 * time.Second,
+			LapTimes:     []time.Duration{10 * time.Second, 12 * time.Second},
+			LapSpeeds:    []float64{100, 1000.0 / 12},
+			Hits:         9,
+			Shots:        10,
+			Shooting:     []string{"XX.XX", "XXXXX"},
+			Positions: []engine.PositionShooting{
+				{Position: config.PositionProne, Stages: 1, Hits: 4, Shots: 5},
+				{Position: config.PositionStanding, Stages: 1, Hits: 5, Shots: 5},
+			},
+		},
+		{
+			CompetitorID:   2,
+			Status:         engine.StatusNotFinished,
+			Shooting:       []string{"X...."},
+			Entry:          models.RosterEntry{CompetitorID: 2, Name: "J. <Doe>", Nation: "NOR"},
+			NotFinishedMsg: "Lost <in> the forest",
+		},
+		{CompetitorID: 3, Status: engine.StatusNotStarted},
+		{CompetitorID: 4, Status: engine.StatusNotStarted, Disqualified: true},
+		{CompetitorID: 5, Status: engine.StatusNotFinished, NotFinishedMsg: "lapped"},
+	}
+
+	at := func(clock string) time.Time {
+		parsed, err := time.Parse(events.TimeLayoutHMSMilli, clock)
+		require.NoError(t, err)
+		return parsed
+	}
+	log := []models.Event{
+		{Time: at("10:00:00.000"), ID: models.EventStart, CompetitorID: 1},
+		{Time: at("10:01:00.000"), ID: models.EventNotContinue, CompetitorID: 2, ExtraParams: []string{"Lost", "<in>", "the", "forest"}},
+		{Time: at("10:02:00.000"), ID: models.EventLapped, CompetitorID: 5, ExtraParams: []string{"1"}},
+	}
+
+	var b strings.Builder
+	require.NoError(t, Render(&b, "Sprint <men>", rows, log))
+	page := b.String()
+
+	assert.Contains(t, page, "<title>Sprint &lt;men&gt;</title>")
+	assert.Contains(t, page, "<th>Lap 2</th>")
+	assert.Contains(t, page, `<td data-sort="22000">00:22.000</td>`)
+	assert.Contains(t, page, "XX.XX XXXXX")
+	assert.Contains(t, page, "9/10<br><small>prone 4/5, standing 5/5</small>")
+	assert.Contains(t, page, "<h2>Did not finish</h2>")
+	assert.Contains(t, page, "<td>2 <small>J. &lt;Doe&gt;, NOR</small></td>")
+	assert.Contains(t, page, "Lost &lt;in&gt; the forest")
+	assert.Contains(t, page, `<td class="comment">lapped</td>`)
+	assert.Contains(t, page, "<h2>Did not start</h2>\n<table>\n<tr><th>Bib</th><th>Start</th></tr>\n<tr><td>3</td>")
+	assert.Contains(t, page, "<h2>Disqualified</h2>\n<table>\n<tr><th>Bib</th><th>Start</th></tr>\n<tr><td>4</td>")
+	assert.Contains(t, page, "<h2>Event log</h2>\n<pre class=\"log\">\n"+
+		"[10:00:00.000] The competitor 1 has started\n"+
+		"[10:01:00.000] The competitor 2 (J. &lt;Doe&gt;, NOR) can`t continue: Lost &lt;in&gt; the forest\n")
+	assert.NotContains(t, page, "<h2>Relay legs</h2>")
+}
+
+func TestRenderRelayLegs(t *testing.T) {
+	rows := []engine.ReportRow{
+		{
+			CompetitorID: 1,
+			Status:       engine.StatusFinished,
+			Rank:         1,
+			TotalTime:    40 * time.Second,
+			LapTimes:     []time.Duration{10 * time.Second, 10 * time.Second, 10 * time.Second, 10 * time.Second},
+			LapSpeeds:    []float64{100, 100, 100, 100},
+			Legs: []engine.ReportRow{
+				{Leg: 1, Athlete: 1, LapTimes: []time.Duration{10 * time.Second, 10 * time.Second}, LapSpeeds: []float64{100, 100}, Hits: 5, Shots: 6, Shooting: []string{"XXXXX"}},
+				{Leg: 2, Athlete: 2, LapTimes: []time.Duration{10 * time.Second, 10 * time.Second}, LapSpeeds: []float64{100, 100}, Hits: 4, Shots: 5, Shooting: []string{"XX.XX"}},
+			},
+		},
+	}
+
+	var b strings.Builder
+	require.NoError(t, Render(&b, "Relay", rows, nil))
+	page := b.String()
+
+	assert.Contains(t, page, "<h2>Relay legs</h2>")
+	assert.Contains(t, page, "<tr>\n<td>1</td>\n<td>1 <small>athlete 1</small></td>\n<td>00:10.000, 00:10.000</td>")
+	assert.Contains(t, page, "<td>2 <small>athlete 2</small></td>")
+	assert.Contains(t, page, `<td class="shooting">XX.XX</td>`)
+	assert.NotContains(t, page, "<h2>Event log</h2>")
+}
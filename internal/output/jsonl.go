@@ -0,0 +1,92 @@
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/events"
+	"github.com/zahartd/biathlon_competitions_system/internal/models"
+)
+
+type jsonRecord struct {
+	Time         string         `json:"time"`
+	EventID      models.EventID `json:"eventId"`
+	Event        string         `json:"event"`
+	CompetitorID int            `json:"competitorId"`
+	Params       map[string]any `json:"params,omitempty"`
+	Hits         int            `json:"hits"` // targets hit by the competitor so far
+	Status       SkipStatus     `json:"status,omitempty"`
+	Violation    string         `json:"violation,omitempty"`
+}
+
+// JSONLogger writes every event as a JSON Lines record. Unlike Logger it
+// keeps events it has no sentence for and incoming events the engine skipped.
+type JSONLogger struct {
+	enc  *json.Encoder
+	hits map[int]int
+}
+
+func NewJSONLogger(w io.Writer) *JSONLogger {
+	return &JSONLogger{enc: json.NewEncoder(w), hits: make(map[int]int)}
+}
+
+func (l *JSONLogger) Write(event models.Event) error {
+	if event.ID == models.EventHit {
+		l.hits[event.CompetitorID]++
+	}
+	return l.enc.Encode(newJSONRecord(event, l.hits[event.CompetitorID]))
+}
+
+// WriteSkipped writes the event with its status and the reason it was
+// skipped. A skipped hit doesn't count.
+func (l *JSONLogger) WriteSkipped(event models.Event, status SkipStatus, reason string) error {
+	record := newJSONRecord(event, l.hits[event.CompetitorID])
+	record.Status, record.Violation = status, reason
+	return l.enc.Encode(record)
+}
+
+func newJSONRecord(event models.Event, hits int) jsonRecord {
+	return jsonRecord{
+		Time:         event.Time.Format(events.TimeLayoutHMSMilli),
+		EventID:      event.ID,
+		Event:        event.ID.String(),
+		CompetitorID: event.CompetitorID,
+		Params:       jsonParams(event),
+		Hits:         hits,
+	}
+}
+
+// jsonParams names the extra params of known events. Params of unknown
+// events are kept as they came.
+func jsonParams(event models.Event) map[string]any {
+	param := func(i int) any {
+		if i >= len(event.ExtraParams) {
+			return nil
+		}
+		if n, err := strconv.Atoi(event.ExtraParams[i]); err == nil {
+			return n
+		}
+		return event.ExtraParams[i]
+	}
+
+	switch event.ID {
+	case models.EventDraw:
+		return map[string]any{"startTime": param(0)}
+	case models.EventFiring:
+		return map[string]any{"range": param(0)}
+	case models.EventHit:
+		return map[string]any{"target": param(0)}
+	case models.EventNotContinue:
+		return map[string]any{"comment": strings.Join(event.ExtraParams, " ")}
+	case models.EventLoopsSkipped:
+		return map[string]any{"skipped": param(0), "owed": param(1)}
+	case models.EventLapped:
+		return map[string]any{"lap": param(0)}
+	}
+	if len(event.ExtraParams) == 0 {
+		return nil
+	}
+	return map[string]any{"raw": event.ExtraParams}
+}
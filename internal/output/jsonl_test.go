@@ -0,0 +1,32 @@
+package output
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/models"
+)
+
+func TestJSONLogger(t *testing.T) {
+	at := time.Date(0, time.January, 1, 9, 49, 33, 123*1e6, time.UTC)
+	var b strings.Builder
+	logger := NewJSONLogger(&b)
+	logger.Write(models.Event{Time: at, ID: models.EventHit, CompetitorID: 1, ExtraParams: []string{"2"}})
+	logger.Write(models.Event{Time: at, ID: models.EventHit, CompetitorID: 1, ExtraParams: []string{"3"}})
+	logger.Write(models.Event{Time: at, ID: models.EventNotContinue, CompetitorID: 1, ExtraParams: []string{"Lost", "skis"}})
+	logger.Write(models.Event{Time: at, ID: 99, CompetitorID: 2, ExtraParams: []string{"x"}})
+	logger.WriteSkipped(models.Event{Time: at, ID: models.EventHit, CompetitorID: 1, ExtraParams: []string{"2"}},
+		SkipRejected, "competitor 1: target 2 has already been hit on this range")
+
+	assert.Equal(t, ""+
+		`{"time":"09:49:33.123","eventId":6,"event":"hit","competitorId":1,"params":{"target":2},"hits":1}`+"\n"+
+		`{"time":"09:49:33.123","eventId":6,"event":"hit","competitorId":1,"params":{"target":3},"hits":2}`+"\n"+
+		`{"time":"09:49:33.123","eventId":11,"event":"not_continue","competitorId":1,"params":{"comment":"Lost skis"},"hits":2}`+"\n"+
+		`{"time":"09:49:33.123","eventId":99,"event":"EventID(99)","competitorId":2,"params":{"raw":["x"]},"hits":0}`+"\n"+
+		`{"time":"09:49:33.123","eventId":6,"event":"hit","competitorId":1,"params":{"target":2},"hits":2,`+
+		`"status":"rejected","violation":"competitor 1: target 2 has already been hit on this range"}`+"\n",
+		b.String())
+}
@@ -10,61 +10,98 @@ import (
 )
 
 type Logger struct {
-	w io.Writer
+	w      io.Writer
+	roster models.Roster
 }
 
-func NewLogger(w io.Writer) *Logger {
-	return &Logger{w: w}
+type LoggerOption func(*Logger)
+
+// WithRoster names competitors in the log, e.g. "The competitor 3 (J. Doe, NOR) has started".
+func WithRoster(roster models.Roster) LoggerOption {
+	return func(l *Logger) {
+		l.roster = roster
+	}
+}
+
+func NewLogger(w io.Writer, opts ...LoggerOption) *Logger {
+	l := &Logger{w: w}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// competitor renders the competitor the way log lines refer to it.
+func (l *Logger) competitor(competitorID int) string {
+	if l.roster == nil {
+		return fmt.Sprintf("competitor(%d)", competitorID)
+	}
+	return "competitor " + l.roster.Label(competitorID)
 }
 
-func (l *Logger) Write(event models.Event) {
+func (l *Logger) Write(event models.Event) error {
 	timestamp := event.Time.Format(events.TimeLayoutHMSMilli)
 	var line string
 	switch event.ID {
 	case models.EventRegister:
-		line = fmt.Sprintf("[%s] The competitor(%d) registered", timestamp, event.CompetitorID)
+		line = fmt.Sprintf("[%s] The %s registered", timestamp, l.competitor(event.CompetitorID))
 	case models.EventDraw:
 		line = fmt.Sprintf(
-			"[%s] The start time for the competitor(%d) was set by a draw to %s",
-			timestamp, event.CompetitorID, event.ExtraParams[0],
+			"[%s] The start time for the %s was set by a draw to %s",
+			timestamp, l.competitor(event.CompetitorID), event.ExtraParams[0],
 		)
 	case models.EventOnLine:
-		line = fmt.Sprintf("[%s] The competitor(%d) is on the start line", timestamp, event.CompetitorID)
+		line = fmt.Sprintf("[%s] The %s is on the start line", timestamp, l.competitor(event.CompetitorID))
 	case models.EventStart:
-		line = fmt.Sprintf("[%s] The competitor(%d) has started", timestamp, event.CompetitorID)
+		line = fmt.Sprintf("[%s] The %s has started", timestamp, l.competitor(event.CompetitorID))
 	case models.EventFiring:
 		line = fmt.Sprintf(
-			"[%s] The competitor(%d) is on the firing range(%s)",
+			"[%s] The %s is on the firing range(%s)",
 			timestamp,
-			event.CompetitorID,
+			l.competitor(event.CompetitorID),
 			event.ExtraParams[0],
 		)
 	case models.EventHit:
 		line = fmt.Sprintf(
-			"[%s] The target(%s) has been hit by competitor(%d)",
-			timestamp, event.ExtraParams[0], event.CompetitorID,
+			"[%s] The target(%s) has been hit by %s",
+			timestamp, event.ExtraParams[0], l.competitor(event.CompetitorID),
 		)
 	case models.EventLeaveFiring:
-		line = fmt.Sprintf("[%s] The competitor(%d) left the firing range", timestamp, event.CompetitorID)
+		line = fmt.Sprintf("[%s] The %s left the firing range", timestamp, l.competitor(event.CompetitorID))
 	case models.EventPenaltyEnter:
-		line = fmt.Sprintf("[%s] The competitor(%d) entered the penalty laps", timestamp, event.CompetitorID)
+		line = fmt.Sprintf("[%s] The %s entered the penalty laps", timestamp, l.competitor(event.CompetitorID))
 	case models.EventPenaltyLeave:
-		line = fmt.Sprintf("[%s] The competitor(%d) left the penalty laps", timestamp, event.CompetitorID)
+		line = fmt.Sprintf("[%s] The %s left the penalty laps", timestamp, l.competitor(event.CompetitorID))
 	case models.EventLapEnd:
-		line = fmt.Sprintf("[%s] The competitor(%d) ended the main lap", timestamp, event.CompetitorID)
+		line = fmt.Sprintf("[%s] The %s ended the main lap", timestamp, l.competitor(event.CompetitorID))
 	case models.EventNotContinue:
 		comment := strings.Join(event.ExtraParams, " ")
 		line = fmt.Sprintf(
-			"[%s] The competitor(%d) can`t continue: %s",
-			timestamp, event.CompetitorID, comment,
+			"[%s] The %s can`t continue: %s",
+			timestamp, l.competitor(event.CompetitorID), comment,
 		)
+	case models.EventExchange:
+		line = fmt.Sprintf("[%s] The %s handed over to the next leg", timestamp, l.competitor(event.CompetitorID))
+	case models.EventSpareRound:
+		line = fmt.Sprintf("[%s] The %s loaded a spare round", timestamp, l.competitor(event.CompetitorID))
 	case models.EventDisqualification:
-		line = fmt.Sprintf("[%s] The competitor(%d) is disqualified", timestamp, event.CompetitorID)
+		line = fmt.Sprintf("[%s] The %s is disqualified", timestamp, l.competitor(event.CompetitorID))
 	case models.EventFinished:
-		line = fmt.Sprintf("[%s] The competitor(%d) has finished", timestamp, event.CompetitorID)
+		line = fmt.Sprintf("[%s] The %s has finished", timestamp, l.competitor(event.CompetitorID))
+	case models.EventLoopsSkipped:
+		line = fmt.Sprintf(
+			"[%s] The %s skipped %s of %s penalty laps",
+			timestamp, l.competitor(event.CompetitorID), event.ExtraParams[0], event.ExtraParams[1],
+		)
+	case models.EventLapped:
+		line = fmt.Sprintf(
+			"[%s] The %s was lapped by the leader on lap %s and pulled out",
+			timestamp, l.competitor(event.CompetitorID), event.ExtraParams[0],
+		)
 	default:
-		return
+		return nil
 	}
 
-	fmt.Fprintln(l.w, line)
+	_, err := fmt.Fprintln(l.w, line)
+	return err
 }
@@ -0,0 +1,44 @@
+package output
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/models"
+)
+
+func TestLoggerRoster(t *testing.T) {
+	at := time.Date(0, time.January, 1, 9, 49, 33, 123*1e6, time.UTC)
+	events := []models.Event{
+		{Time: at, ID: models.EventStart, CompetitorID: 3},
+		{Time: at, ID: models.EventHit, CompetitorID: 3, ExtraParams: []string{"2"}},
+		{Time: at, ID: models.EventStart, CompetitorID: 4},
+	}
+
+	var plain strings.Builder
+	logger := NewLogger(&plain)
+	for _, event := range events {
+		assert.NoError(t, logger.Write(event))
+	}
+	assert.Equal(t, ""+
+		"[09:49:33.123] The competitor(3) has started\n"+
+		"[09:49:33.123] The target(2) has been hit by competitor(3)\n"+
+		"[09:49:33.123] The competitor(4) has started\n",
+		plain.String())
+
+	var named strings.Builder
+	logger = NewLogger(&named, WithRoster(models.Roster{
+		3: {CompetitorID: 3, Name: "J. Doe", Nation: "NOR"},
+	}))
+	for _, event := range events {
+		assert.NoError(t, logger.Write(event))
+	}
+	assert.Equal(t, ""+
+		"[09:49:33.123] The competitor 3 (J. Doe, NOR) has started\n"+
+		"[09:49:33.123] The target(2) has been hit by competitor 3 (J. Doe, NOR)\n"+
+		"[09:49:33.123] The competitor 4 has started\n",
+		named.String())
+}
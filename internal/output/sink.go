@@ -0,0 +1,101 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/models"
+)
+
+// EventSink receives every event the engine accepts or emits.
+type EventSink interface {
+	Write(event models.Event) error
+}
+
+// SkipStatus tells why the engine did not accept an incoming event.
+type SkipStatus string
+
+const (
+	SkipRejected SkipStatus = "rejected" // the event breaks the rules
+	SkipIgnored  SkipStatus = "ignored"  // the competitor is out of the race
+)
+
+// SkipSink is an EventSink that also records incoming events the engine did
+// not accept, together with the reason.
+type SkipSink interface {
+	EventSink
+	WriteSkipped(event models.Event, status SkipStatus, reason string) error
+}
+
+// MultiSink fans events out to several sinks. A failing sink doesn't keep the
+// event from the others.
+type MultiSink struct {
+	sinks []EventSink
+}
+
+func NewMultiSink(sinks ...EventSink) *MultiSink {
+	return &MultiSink{sinks: sinks}
+}
+
+// Write passes the event to every sink and returns the errors of the sinks
+// that failed, each wrapped in a SinkError.
+func (m *MultiSink) Write(event models.Event) error {
+	var errs []error
+	for i, sink := range m.sinks {
+		if err := sink.Write(event); err != nil {
+			errs = append(errs, &SinkError{Index: i, Sink: sink, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WriteSkipped passes the skipped event to every sink that records them.
+func (m *MultiSink) WriteSkipped(event models.Event, status SkipStatus, reason string) error {
+	var errs []error
+	for i, sink := range m.sinks {
+		skipSink, ok := sink.(SkipSink)
+		if !ok {
+			continue
+		}
+		if err := skipSink.WriteSkipped(event, status, reason); err != nil {
+			errs = append(errs, &SinkError{Index: i, Sink: sink, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SinkError is a failure of one sink of a MultiSink.
+type SinkError struct {
+	Index int
+	Sink  EventSink
+	Err   error
+}
+
+func (e *SinkError) Error() string {
+	return fmt.Sprintf("sink %d (%T): %s", e.Index, e.Sink, e.Err)
+}
+
+func (e *SinkError) Unwrap() error {
+	return e.Err
+}
+
+// MemorySink keeps events in memory, e.g. to inspect them in tests.
+type MemorySink struct {
+	mu     sync.Mutex
+	events []models.Event
+}
+
+func (s *MemorySink) Write(event models.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+// Events returns a copy of the events written so far.
+func (s *MemorySink) Events() []models.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.Event(nil), s.events...)
+}
@@ -0,0 +1,50 @@
+package output
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/models"
+)
+
+type failingSink struct{}
+
+func (failingSink) Write(models.Event) error {
+	return errors.New("connection reset")
+}
+
+func TestMultiSink(t *testing.T) {
+	var text strings.Builder
+	memory := &MemorySink{}
+	sink := NewMultiSink(failingSink{}, NewLogger(&text), memory)
+
+	event := models.Event{ID: models.EventRegister, CompetitorID: 1}
+	err := sink.Write(event)
+
+	var sinkErr *SinkError
+	require.ErrorAs(t, err, &sinkErr)
+	assert.Equal(t, 0, sinkErr.Index)
+	assert.EqualError(t, err, "sink 0 (output.failingSink): connection reset")
+
+	assert.Equal(t, "[00:00:00.000] The competitor(1) registered\n", text.String())
+	assert.Equal(t, []models.Event{event}, memory.Events())
+
+	assert.NoError(t, NewMultiSink(memory).Write(event))
+}
+
+func TestMultiSinkSkipped(t *testing.T) {
+	var text, jsonl strings.Builder
+	memory := &MemorySink{}
+	sink := NewMultiSink(NewLogger(&text), memory, NewJSONLogger(&jsonl))
+
+	event := models.Event{ID: models.EventOnLine, CompetitorID: 1}
+	require.NoError(t, sink.WriteSkipped(event, SkipIgnored, "competitor is out of the race"))
+
+	assert.Empty(t, text.String(), "the text log has accepted events only")
+	assert.Empty(t, memory.Events())
+	assert.Contains(t, jsonl.String(), `"status":"ignored","violation":"competitor is out of the race"`)
+}
@@ -0,0 +1,147 @@
+// Package roster loads the athletes behind competitor IDs from a CSV or JSON
+// file.
+package roster
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/models"
+)
+
+// Load reads the roster file, CSV or JSON by its extension.
+func Load(path string) (models.Roster, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		return ReadCSV(f)
+	case ".json":
+		return ReadJSON(f)
+	default:
+		return nil, fmt.Errorf("unknown roster format %q, expected .csv or .json", ext)
+	}
+}
+
+// csvColumns are the columns a CSV roster may have; id is required.
+var csvColumns = []string{"id", "bib", "name", "nation", "team", "category"}
+
+// ReadCSV reads a roster with a header row naming its columns, e.g.
+// "id,bib,name,nation,team,category".
+func ReadCSV(r io.Reader) (models.Roster, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("empty roster")
+	}
+
+	index := make(map[string]int)
+	for i, column := range records[0] {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if !slices.Contains(csvColumns, column) {
+			return nil, fmt.Errorf("unknown roster column %q", column)
+		}
+		index[column] = i
+	}
+	if _, ok := index["id"]; !ok {
+		return nil, errors.New("roster has no id column")
+	}
+	field := func(record []string, column string) string {
+		if i, ok := index[column]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	roster := make(models.Roster)
+	for line, record := range records[1:] {
+		id, err := strconv.Atoi(field(record, "id"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid competitor ID on roster line %d: %w", line+2, err)
+		}
+		entry := models.RosterEntry{
+			CompetitorID: id,
+			Bib:          field(record, "bib"),
+			Name:         field(record, "name"),
+			Nation:       field(record, "nation"),
+			Team:         field(record, "team"),
+			Category:     field(record, "category"),
+		}
+		if err := add(roster, entry); err != nil {
+			return nil, err
+		}
+	}
+	return roster, nil
+}
+
+type jsonEntry struct {
+	ID       int     `json:"id"`
+	Bib      jsonBib `json:"bib"`
+	Name     string  `json:"name"`
+	Nation   string  `json:"nation"`
+	Team     string  `json:"team"`
+	Category string  `json:"category"`
+}
+
+// jsonBib accepts a bib given as a number or as a string.
+type jsonBib string
+
+func (b *jsonBib) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		*b = jsonBib(str)
+		return nil
+	}
+	var num json.Number
+	if err := json.Unmarshal(data, &num); err != nil {
+		return fmt.Errorf("invalid bib %s", data)
+	}
+	*b = jsonBib(num)
+	return nil
+}
+
+// ReadJSON reads a roster given as an array of objects with id, bib, name,
+// nation, team and category fields.
+func ReadJSON(r io.Reader) (models.Roster, error) {
+	var entries []jsonEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, err
+	}
+	roster := make(models.Roster)
+	for _, e := range entries {
+		entry := models.RosterEntry{
+			CompetitorID: e.ID,
+			Bib:          string(e.Bib),
+			Name:         e.Name,
+			Nation:       e.Nation,
+			Team:         e.Team,
+			Category:     e.Category,
+		}
+		if err := add(roster, entry); err != nil {
+			return nil, err
+		}
+	}
+	return roster, nil
+}
+
+func add(roster models.Roster, entry models.RosterEntry) error {
+	if _, ok := roster[entry.CompetitorID]; ok {
+		return fmt.Errorf("competitor %d is on the roster twice", entry.CompetitorID)
+	}
+	roster[entry.CompetitorID] = entry
+	return nil
+}
@@ -0,0 +1,67 @@
+package roster
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/models"
+)
+
+func TestReadCSV(t *testing.T) {
+	roster, err := ReadCSV(strings.NewReader("" +
+		"id,name,nation,bib,category\n" +
+		"3,J. Doe,NOR,12,Men\n" +
+		"4, A. Roe ,SWE,,Women\n"))
+	require.NoError(t, err)
+	assert.Equal(t, models.Roster{
+		3: {CompetitorID: 3, Bib: "12", Name: "J. Doe", Nation: "NOR", Category: "Men"},
+		4: {CompetitorID: 4, Name: "A. Roe", Nation: "SWE", Category: "Women"},
+	}, roster)
+
+	for name, input := range map[string]string{
+		"no id column":   "name,nation\nJ. Doe,NOR\n",
+		"unknown column": "id,club\n3,Oslo\n",
+		"bad id":         "id,name\nthree,J. Doe\n",
+		"duplicate id":   "id,name\n3,J. Doe\n3,A. Roe\n",
+		"empty":          "",
+	} {
+		_, err := ReadCSV(strings.NewReader(input))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestReadJSON(t *testing.T) {
+	roster, err := ReadJSON(strings.NewReader(`[
+        {"id": 3, "bib": 12, "name": "J. Doe", "nation": "NOR", "team": "Norway", "category": "Men"},
+        {"id": 4, "bib": "A4", "name": "A. Roe"}
+    ]`))
+	require.NoError(t, err)
+	assert.Equal(t, models.Roster{
+		3: {CompetitorID: 3, Bib: "12", Name: "J. Doe", Nation: "NOR", Team: "Norway", Category: "Men"},
+		4: {CompetitorID: 4, Bib: "A4", Name: "A. Roe"},
+	}, roster)
+
+	_, err = ReadJSON(strings.NewReader(`[{"id": 3}, {"id": 3}]`))
+	assert.ErrorContains(t, err, "on the roster twice")
+	_, err = ReadJSON(strings.NewReader(`[{"id": 3, "bib": true}]`))
+	assert.Error(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "roster.csv")
+	require.NoError(t, os.WriteFile(csvPath, []byte("id,name\n3,J. Doe\n"), 0o644))
+	roster, err := Load(csvPath)
+	require.NoError(t, err)
+	assert.Equal(t, "3 (J. Doe)", roster.Label(3))
+
+	txtPath := filepath.Join(dir, "roster.txt")
+	require.NoError(t, os.WriteFile(txtPath, []byte("3 J. Doe\n"), 0o644))
+	_, err = Load(txtPath)
+	assert.ErrorContains(t, err, "unknown roster format")
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/models"
+)
+
+// Message is an event numbered in the order the hub received it.
+type Message struct {
+	Seq   uint64
+	Event models.Event
+}
+
+const (
+	TopicAll      = ""
+	TopicShooting = "shooting" // firing range events
+	TopicFinishes = "finishes" // finish events
+)
+
+// Filter selects the messages a subscriber receives.
+type Filter struct {
+	CompetitorID int // 0 for every competitor
+	Topic        string
+}
+
+// ParseFilter reads a filter from the competitor and topic query values.
+func ParseFilter(competitor, topic string) (Filter, error) {
+	var f Filter
+	if competitor != "" {
+		id, err := strconv.Atoi(competitor)
+		if err != nil {
+			return Filter{}, fmt.Errorf("invalid competitor ID %s", competitor)
+		}
+		f.CompetitorID = id
+	}
+	switch topic {
+	case TopicAll, TopicShooting, TopicFinishes:
+		f.Topic = topic
+	default:
+		return Filter{}, fmt.Errorf("unknown topic %q", topic)
+	}
+	return f, nil
+}
+
+func (f Filter) Match(event models.Event) bool {
+	if f.CompetitorID != 0 && event.CompetitorID != f.CompetitorID {
+		return false
+	}
+	switch f.Topic {
+	case TopicShooting:
+		switch event.ID {
+		case models.EventFiring, models.EventHit, models.EventLeaveFiring, models.EventSpareRound:
+			return true
+		}
+		return false
+	case TopicFinishes:
+		return event.ID == models.EventFinished
+	}
+	return true
+}
+
+// subscriberBuffer is how many messages a subscriber may fall behind before
+// it is dropped. A dropped client reconnects and resumes from its last seq.
+const subscriberBuffer = 256
+
+// Hub is an event sink that numbers events, keeps the latest of them for
+// resuming clients and broadcasts them to subscribers.
+type Hub struct {
+	mu      sync.Mutex
+	seq     uint64
+	history []Message // ring of the last cap(history) messages
+	subs    map[*Subscription]struct{}
+}
+
+func NewHub(historySize int) *Hub {
+	return &Hub{
+		history: make([]Message, 0, historySize),
+		subs:    make(map[*Subscription]struct{}),
+	}
+}
+
+func (h *Hub) Write(event models.Event) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.seq++
+	msg := Message{Seq: h.seq, Event: event}
+	if len(h.history) < cap(h.history) {
+		h.history = append(h.history, msg)
+	} else if cap(h.history) > 0 {
+		h.history[int((h.seq-1)%uint64(cap(h.history)))] = msg
+	}
+
+	for sub := range h.subs {
+		if !sub.filter.Match(event) {
+			continue
+		}
+		select {
+		case sub.ch <- msg:
+		default:
+			h.drop(sub)
+		}
+	}
+	return nil
+}
+
+// Subscription delivers the messages matching its filter on C. C is closed
+// when the subscriber falls behind or the subscription is closed.
+type Subscription struct {
+	C      <-chan Message
+	ch     chan Message
+	filter Filter
+	hub    *Hub
+	// Reset is set if messages after the resume point are no longer kept (or
+	// were never sent), so the client has to refetch the full event log.
+	Reset bool
+}
+
+// Subscribe returns the kept messages after the given seq that match the
+// filter, followed by a subscription for new ones. A seq of 0 subscribes a
+// new client.
+func (h *Hub) Subscribe(filter Filter, after uint64) ([]Message, *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// the oldest message sits right after the newest one once the ring is full
+	oldest := 0
+	if len(h.history) == cap(h.history) && len(h.history) > 0 {
+		oldest = int(h.seq % uint64(len(h.history)))
+	}
+	var backlog []Message
+	for i := range h.history {
+		msg := h.history[(oldest+i)%len(h.history)]
+		if msg.Seq > after && filter.Match(msg.Event) {
+			backlog = append(backlog, msg)
+		}
+	}
+
+	ch := make(chan Message, subscriberBuffer)
+	sub := &Subscription{C: ch, ch: ch, filter: filter, hub: h}
+	if firstKept := h.seq - uint64(len(h.history)) + 1; after > 0 && (after+1 < firstKept || after > h.seq) {
+		sub.Reset = true
+	}
+	h.subs[sub] = struct{}{}
+	return backlog, sub
+}
+
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.drop(s)
+}
+
+func (h *Hub) drop(sub *Subscription) {
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.ch)
+	}
+}
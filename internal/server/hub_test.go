@@ -0,0 +1,118 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/models"
+)
+
+func seqs(msgs []Message) []uint64 {
+	var out []uint64
+	for _, msg := range msgs {
+		out = append(out, msg.Seq)
+	}
+	return out
+}
+
+func TestHubResume(t *testing.T) {
+	hub := NewHub(3)
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, hub.Write(models.Event{ID: models.EventHit, CompetitorID: i}))
+	}
+
+	backlog, sub := hub.Subscribe(Filter{}, 0)
+	defer sub.Close()
+	assert.Equal(t, []uint64{3, 4, 5}, seqs(backlog), "only the last messages are kept")
+
+	backlog, sub2 := hub.Subscribe(Filter{}, 4)
+	defer sub2.Close()
+	assert.Equal(t, []uint64{5}, seqs(backlog))
+
+	require.NoError(t, hub.Write(models.Event{ID: models.EventFinished, CompetitorID: 1}))
+	assert.Equal(t, uint64(6), (<-sub.C).Seq)
+	assert.Equal(t, uint64(6), (<-sub2.C).Seq)
+	assert.False(t, sub.Reset, "a new client doesn't resume")
+	assert.False(t, sub2.Reset)
+}
+
+func TestHubResumeGap(t *testing.T) {
+	hub := NewHub(3)
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, hub.Write(models.Event{ID: models.EventHit, CompetitorID: i}))
+	}
+
+	tests := []struct {
+		after     uint64
+		wantReset bool
+	}{
+		{after: 1, wantReset: true},
+		{after: 2},
+		{after: 5},
+		{after: 9, wantReset: true}, // seq of an earlier server run
+	}
+	for _, tc := range tests {
+		_, sub := hub.Subscribe(Filter{}, tc.after)
+		sub.Close()
+		assert.Equal(t, tc.wantReset, sub.Reset, "after %d", tc.after)
+	}
+}
+
+func TestHubFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []uint64
+	}{
+		{name: "all", filter: Filter{}, want: []uint64{1, 2, 3, 4}},
+		{name: "competitor", filter: Filter{CompetitorID: 2}, want: []uint64{3, 4}},
+		{name: "shooting", filter: Filter{Topic: TopicShooting}, want: []uint64{2, 3}},
+		{name: "finishes", filter: Filter{Topic: TopicFinishes}, want: []uint64{4}},
+	}
+
+	hub := NewHub(10)
+	for _, event := range []models.Event{
+		{ID: models.EventStart, CompetitorID: 1},
+		{ID: models.EventHit, CompetitorID: 1},
+		{ID: models.EventFiring, CompetitorID: 2},
+		{ID: models.EventFinished, CompetitorID: 2},
+	} {
+		require.NoError(t, hub.Write(event))
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			backlog, sub := hub.Subscribe(tc.filter, 0)
+			sub.Close()
+			assert.Equal(t, tc.want, seqs(backlog))
+		})
+	}
+}
+
+func TestHubDropsSlowSubscriber(t *testing.T) {
+	hub := NewHub(0)
+	_, sub := hub.Subscribe(Filter{}, 0)
+	for range subscriberBuffer + 1 {
+		require.NoError(t, hub.Write(models.Event{ID: models.EventHit}))
+	}
+
+	received := 0
+	for range sub.C {
+		received++
+	}
+	assert.Equal(t, subscriberBuffer, received, "the channel is closed once the buffer overflows")
+	sub.Close()
+}
+
+func TestParseFilter(t *testing.T) {
+	f, err := ParseFilter("3", "shooting")
+	require.NoError(t, err)
+	assert.Equal(t, Filter{CompetitorID: 3, Topic: TopicShooting}, f)
+
+	_, err = ParseFilter("x", "")
+	assert.Error(t, err)
+	_, err = ParseFilter("", "laps")
+	assert.Error(t, err)
+}
@@ -0,0 +1,270 @@
+// Package server exposes a running engine over HTTP for live results.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/engine"
+	"github.com/zahartd/biathlon_competitions_system/internal/events"
+	"github.com/zahartd/biathlon_competitions_system/internal/models"
+	"github.com/zahartd/biathlon_competitions_system/internal/output"
+)
+
+// Server serializes access to the engine, which is not safe for concurrent use.
+type Server struct {
+	mu     sync.Mutex
+	engine *engine.Engine
+	parser *events.Parser
+	log    *output.MemorySink
+	hub    *Hub
+}
+
+// New wraps the engine. The log and the hub must be sinks the engine writes
+// to: the log serves the event log, the hub pushes events to clients.
+func New(e *engine.Engine, log *output.MemorySink, hub *Hub) *Server {
+	return &Server{engine: e, parser: events.NewParser(), log: log, hub: hub}
+}
+
+// ParseError is returned by ProcessLine for a line that is not an event.
+type ParseError struct {
+	Line string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("failed to parse event %s: %s", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ProcessLine parses an event line and passes it to the engine.
+func (s *Server) ProcessLine(line string) error {
+	event, err := s.parser.ParseEvent(line)
+	if err != nil {
+		return &ParseError{Line: line, Err: err}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.engine.ProcessEvent(event)
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /startlist", s.handleStartList)
+	mux.HandleFunc("GET /competitors/{id}", s.handleCompetitor)
+	mux.HandleFunc("GET /standings", s.handleStandings)
+	mux.HandleFunc("GET /events", s.handleEvents)
+	mux.HandleFunc("POST /events", s.handlePostEvents)
+	mux.HandleFunc("GET /stream", s.handleStream)
+	mux.HandleFunc("GET /ws", s.handleWebSocket)
+	return mux
+}
+
+type startListEntry struct {
+	CompetitorID   int    `json:"competitorId"`
+	ScheduledStart string `json:"scheduledStart"`
+}
+
+func (s *Server) handleStartList(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	entries := s.engine.StartList()
+	s.mu.Unlock()
+
+	resp := make([]startListEntry, 0, len(entries))
+	for _, entry := range entries {
+		resp = append(resp, startListEntry{
+			CompetitorID:   entry.CompetitorID,
+			ScheduledStart: formatClock(entry.ScheduledStart),
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+type competitor struct {
+	CompetitorID   int      `json:"competitorId"`
+	Phase          string   `json:"phase"`
+	ScheduledStart string   `json:"scheduledStart,omitempty"`
+	ActualStart    string   `json:"actualStart,omitempty"`
+	LapsDone       int      `json:"lapsDone"`
+	OnRange        bool     `json:"onRange"`
+	InPenalty      bool     `json:"inPenalty"`
+	Hits           int      `json:"hits"`
+	Shots          int      `json:"shots"`
+	Shooting       []string `json:"shooting"`
+	Violations     []string `json:"violations,omitempty"`
+}
+
+func (s *Server) handleCompetitor(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid competitor ID %s", r.PathValue("id")))
+		return
+	}
+	s.mu.Lock()
+	status, ok := s.engine.Competitor(id)
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("competitor %d not found", id))
+		return
+	}
+
+	resp := competitor{
+		CompetitorID:   status.CompetitorID,
+		Phase:          status.Phase.String(),
+		ScheduledStart: formatClock(status.ScheduledStart),
+		ActualStart:    formatClock(status.ActualStart),
+		LapsDone:       status.LapsDone,
+		OnRange:        status.OnRange,
+		InPenalty:      status.InPenalty,
+		Hits:           status.Hits,
+		Shots:          status.Shots,
+		Shooting:       status.Shooting,
+		Violations:     status.Violations,
+	}
+	if resp.Shooting == nil {
+		resp.Shooting = []string{}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+type standing struct {
+	Rank         int      `json:"rank"`
+	CompetitorID int      `json:"competitorId"`
+	Laps         int      `json:"laps"`
+	Stages       int      `json:"stages"`
+	Finished     bool     `json:"finished"`
+	Time         duration `json:"time"`
+	Behind       duration `json:"behind"`
+}
+
+type duration struct {
+	Ms        int64  `json:"ms"`
+	Formatted string `json:"formatted"`
+}
+
+func newDuration(d time.Duration) duration {
+	return duration{Ms: d.Milliseconds(), Formatted: engine.FormatDuration(d)}
+}
+
+// handleStandings returns the current standings, the order at the clock
+// time given as at or at the checkpoint given as checkpoint (lap:N, stage:K).
+func (s *Server) handleStandings(w http.ResponseWriter, r *http.Request) {
+	standingsOf := func(e *engine.Engine) []engine.Standing { return e.Standings() }
+	if at := r.URL.Query().Get("at"); at != "" {
+		t, err := time.Parse(events.TimeLayoutHMSMilli, at)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid time %s, expected %s", at, events.TimeLayoutHMSMilli))
+			return
+		}
+		standingsOf = func(e *engine.Engine) []engine.Standing { return e.StandingsAt(t) }
+	}
+	if checkpoint := r.URL.Query().Get("checkpoint"); checkpoint != "" {
+		cp, err := engine.ParseCheckpoint(checkpoint)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		standingsOf = func(e *engine.Engine) []engine.Standing { return e.StandingsAfter(cp) }
+	}
+
+	s.mu.Lock()
+	standings := standingsOf(s.engine)
+	s.mu.Unlock()
+
+	resp := make([]standing, 0, len(standings))
+	for _, st := range standings {
+		resp = append(resp, standing{
+			Rank:         st.Rank,
+			CompetitorID: st.CompetitorID,
+			Laps:         st.Laps,
+			Stages:       st.Stages,
+			Finished:     st.Finished,
+			Time:         newDuration(st.Time),
+			Behind:       newDuration(st.Behind),
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+type event struct {
+	Time         string         `json:"time"`
+	EventID      models.EventID `json:"eventId"`
+	Event        string         `json:"event"`
+	CompetitorID int            `json:"competitorId"`
+	ExtraParams  []string       `json:"extraParams,omitempty"`
+}
+
+func newEvent(e models.Event) event {
+	return event{
+		Time:         formatClock(e.Time),
+		EventID:      e.ID,
+		Event:        e.ID.String(),
+		CompetitorID: e.CompetitorID,
+		ExtraParams:  e.ExtraParams,
+	}
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	log := s.log.Events()
+	resp := make([]event, 0, len(log))
+	for _, e := range log {
+		resp = append(resp, newEvent(e))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handlePostEvents processes event lines of the request body in order and
+// stops at the first one that fails.
+func (s *Server) handlePostEvents(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	accepted := 0
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if err := s.ProcessLine(line); err != nil {
+			status := http.StatusUnprocessableEntity
+			var parseErr *ParseError
+			if errors.As(err, &parseErr) {
+				status = http.StatusBadRequest
+			}
+			writeJSON(w, status, map[string]any{"accepted": accepted, "error": err.Error()})
+			return
+		}
+		accepted++
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"accepted": accepted})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func formatClock(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(events.TimeLayoutHMSMilli)
+}
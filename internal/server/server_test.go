@@ -0,0 +1,115 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zahartd/biathlon_competitions_system/internal/config"
+	"github.com/zahartd/biathlon_competitions_system/internal/engine"
+	"github.com/zahartd/biathlon_competitions_system/internal/output"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	cfg := config.Config{
+		Laps:        1,
+		LapLen:      1000,
+		PenaltyLen:  100,
+		FiringLines: 1,
+		Start:       time.Date(0, time.January, 1, 10, 0, 0, 0, time.UTC),
+		StartDelta:  30 * time.Second,
+	}
+	log, hub := &output.MemorySink{}, NewHub(16)
+	e := engine.NewEngine(cfg, output.NewMultiSink(output.NewLogger(&bytes.Buffer{}), log, hub))
+	ts := httptest.NewServer(New(e, log, hub).Handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func getJSON(t *testing.T, url string, v any) int {
+	t.Helper()
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+	return resp.StatusCode
+}
+
+func postEvents(t *testing.T, url string, lines ...string) (int, map[string]any) {
+	t.Helper()
+	resp, err := http.Post(url+"/events", "text/plain", strings.NewReader(strings.Join(lines, "\n")))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var body map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	return resp.StatusCode, body
+}
+
+func TestServer(t *testing.T) {
+	ts := newTestServer(t)
+
+	status, body := postEvents(t, ts.URL,
+		"[09:00:00.000] 1 1",
+		"[09:01:00.000] 2 1 10:00:00.000",
+		"[09:59:00.000] 3 1",
+		"[10:00:00.000] 4 1",
+		"[10:02:00.000] 5 1 1",
+		"[10:02:01.000] 6 1 2",
+	)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, 6.0, body["accepted"])
+
+	var startList []startListEntry
+	assert.Equal(t, http.StatusOK, getJSON(t, ts.URL+"/startlist", &startList))
+	assert.Equal(t, []startListEntry{{CompetitorID: 1, ScheduledStart: "10:00:00.000"}}, startList)
+
+	var c competitor
+	assert.Equal(t, http.StatusOK, getJSON(t, ts.URL+"/competitors/1", &c))
+	assert.Equal(t, "on firing range", c.Phase)
+	assert.True(t, c.OnRange)
+	assert.Equal(t, []string{".X..."}, c.Shooting)
+
+	var notFound map[string]string
+	assert.Equal(t, http.StatusNotFound, getJSON(t, ts.URL+"/competitors/2", &notFound))
+
+	var log []event
+	assert.Equal(t, http.StatusOK, getJSON(t, ts.URL+"/events", &log))
+	require.Len(t, log, 6)
+	assert.Equal(t, "hit", log[5].Event)
+	assert.Equal(t, []string{"2"}, log[5].ExtraParams)
+
+	status, body = postEvents(t, ts.URL, "[10:03:00.000] 7 1", "garbage")
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Equal(t, 1.0, body["accepted"])
+
+	status, _ = postEvents(t, ts.URL, "[10:03:01.000] 7 1")
+	assert.Equal(t, http.StatusOK, status, "lenient mode skips the event")
+
+	status, _ = postEvents(t, ts.URL, "[10:05:00.000] 10 1")
+	assert.Equal(t, http.StatusOK, status)
+
+	var standings []standing
+	assert.Equal(t, http.StatusOK, getJSON(t, ts.URL+"/standings", &standings))
+	require.Len(t, standings, 1)
+	assert.Equal(t, 1, standings[0].Laps)
+	assert.Equal(t, int64(5*60*1000), standings[0].Time.Ms)
+
+	assert.Equal(t, http.StatusOK, getJSON(t, ts.URL+"/standings?checkpoint=stage:1", &standings))
+	require.Len(t, standings, 1)
+	assert.Equal(t, int64(3*60*1000), standings[0].Time.Ms)
+
+	assert.Equal(t, http.StatusOK, getJSON(t, ts.URL+"/standings?at=10:01:00.000", &standings))
+	require.Len(t, standings, 1)
+	assert.Zero(t, standings[0].Stages)
+
+	var bad map[string]string
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, ts.URL+"/standings?checkpoint=lap", &bad))
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// heartbeatInterval keeps idle push connections open through proxies.
+const heartbeatInterval = 15 * time.Second
+
+// resetMessage tells a resuming client that messages it missed are no longer
+// kept and it should refetch GET /events.
+const resetMessage = `{"reset":true}`
+
+type message struct {
+	Seq uint64 `json:"seq"`
+	event
+}
+
+func newMessage(msg Message) message {
+	return message{Seq: msg.Seq, event: newEvent(msg.Event)}
+}
+
+// subscribe reads the filter and the resume point of a push request: the
+// seq of the last message the client got, from the since query value or the
+// Last-Event-ID header of a reconnecting event source.
+func (s *Server) subscribe(r *http.Request) ([]Message, *Subscription, error) {
+	query := r.URL.Query()
+	filter, err := ParseFilter(query.Get("competitor"), query.Get("topic"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	since := query.Get("since")
+	if since == "" {
+		since = r.Header.Get("Last-Event-ID")
+	}
+	var after uint64
+	if since != "" {
+		if after, err = strconv.ParseUint(since, 10, 64); err != nil {
+			return nil, nil, fmt.Errorf("invalid seq %s", since)
+		}
+	}
+
+	backlog, sub := s.hub.Subscribe(filter, after)
+	return backlog, sub, nil
+}
+
+// handleStream pushes messages as Server-Sent Events. The event id is the
+// message seq, so a browser EventSource resumes by itself.
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+	backlog, sub, err := s.subscribe(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(msg Message) error {
+		data, err := json.Marshal(newMessage(msg))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", msg.Seq, msg.Event.ID, data)
+		return err
+	}
+	if sub.Reset {
+		if _, err := fmt.Fprintf(w, "event: reset\ndata: %s\n\n", resetMessage); err != nil {
+			return
+		}
+	}
+	for _, msg := range backlog {
+		if err := send(msg); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case msg, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if err := send(msg); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var raceLines = []string{
+	"[09:00:00.000] 1 1",
+	"[09:01:00.000] 2 1 10:00:00.000",
+	"[09:59:00.000] 3 1",
+	"[10:00:00.000] 4 1",
+	"[10:02:00.000] 5 1 1",
+	"[10:02:01.000] 6 1 2",
+}
+
+func TestStream(t *testing.T) {
+	ts := newTestServer(t)
+	status, _ := postEvents(t, ts.URL, raceLines...)
+	require.Equal(t, http.StatusOK, status)
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/stream?topic=shooting", nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "5")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	readEvent := func() []string {
+		var lines []string
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			line = strings.TrimSuffix(line, "\n")
+			if line == "" {
+				return lines
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	got := readEvent()
+	require.Len(t, got, 3)
+	assert.Equal(t, "id: 6", got[0], "the backlog resumes after Last-Event-ID")
+	assert.Equal(t, "event: hit", got[1])
+
+	postEvents(t, ts.URL, "[10:02:30.000] 7 1")
+	got = readEvent()
+	require.Len(t, got, 3)
+	assert.Equal(t, "id: 7", got[0])
+	var msg message
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(got[2], "data: ")), &msg))
+	assert.Equal(t, uint64(7), msg.Seq)
+	assert.Equal(t, "leave_firing", msg.Event)
+}
+
+func TestStreamReset(t *testing.T) {
+	ts := newTestServer(t)
+	postEvents(t, ts.URL, raceLines...)
+
+	resp, err := http.Get(ts.URL + "/stream?since=99")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 3 {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+	assert.Equal(t, []string{"event: reset", `data: {"reset":true}`, ""}, lines)
+}
+
+func TestWebSocket(t *testing.T) {
+	ts := newTestServer(t)
+	postEvents(t, ts.URL, raceLines...)
+
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+	conn, err := net.Dial("tcp", u.Host)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = io.WriteString(conn, "GET /ws?competitor=1&since=4 HTTP/1.1\r\n"+
+		"Host: "+u.Host+"\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"+
+		"Sec-WebSocket-Version: 13\r\n\r\n")
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+
+	readMessage := func() message {
+		var head [2]byte
+		_, err := io.ReadFull(reader, head[:])
+		require.NoError(t, err)
+		require.Equal(t, byte(0x80|wsOpText), head[0])
+		payload := make([]byte, head[1]&0x7F)
+		if head[1]&0x7F == 126 {
+			var ext [2]byte
+			_, err = io.ReadFull(reader, ext[:])
+			require.NoError(t, err)
+			payload = make([]byte, binary.BigEndian.Uint16(ext[:]))
+		}
+		_, err = io.ReadFull(reader, payload)
+		require.NoError(t, err)
+		var msg message
+		require.NoError(t, json.Unmarshal(payload, &msg))
+		return msg
+	}
+
+	assert.Equal(t, uint64(5), readMessage().Seq)
+	assert.Equal(t, uint64(6), readMessage().Seq)
+
+	// masked close frame with an empty payload
+	_, err = conn.Write([]byte{0x80 | wsOpClose, 0x80, 1, 2, 3, 4})
+	require.NoError(t, err)
+	var head [2]byte
+	_, err = io.ReadFull(reader, head[:])
+	require.NoError(t, err)
+	assert.Equal(t, byte(0x80|wsOpClose), head[0])
+}
+
+func TestWebSocketFrameTooLong(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	ws := &wsConn{conn: server, rw: bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server))}
+
+	// masked binary frame claiming a 2^63 byte payload
+	frame := []byte{0x82, 0x80 | 127}
+	frame = binary.BigEndian.AppendUint64(frame, 1<<63)
+	frame = append(frame, 1, 2, 3, 4)
+	go client.Write(frame)
+
+	_, _, err := ws.readFrame()
+	assert.EqualError(t, err, "data frame too long")
+}
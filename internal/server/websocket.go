@@ -0,0 +1,257 @@
+package server
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A minimal RFC 6455 server: it pushes text messages, answers pings and
+// closes on the client close frame. Client data frames are ignored.
+
+const (
+	wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	wsOpText  = 0x1
+	wsOpClose = 0x8
+	wsOpPing  = 0x9
+	wsOpPong  = 0xA
+
+	// wsMaxControlPayload is the limit RFC 6455 puts on control frames.
+	wsMaxControlPayload = 125
+	// wsMaxDataPayload bounds the client data frames the push stream skips.
+	wsMaxDataPayload = 64 << 10
+
+	// wsReadTimeout is how long a client may stay silent. Clients answer the
+	// heartbeat pings, so a live connection never is.
+	wsReadTimeout = 4 * heartbeatInterval
+)
+
+type wsConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+	mu   sync.Mutex // serializes frame writes
+}
+
+// handshakeKey validates the opening handshake request and returns its key.
+func handshakeKey(w http.ResponseWriter, r *http.Request) (string, error) {
+	if !headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") {
+		return "", errors.New("not a websocket handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return "", errors.New("unsupported websocket version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return "", errors.New("missing Sec-WebSocket-Key")
+	}
+	if _, ok := w.(http.Hijacker); !ok {
+		return "", errors.New("connection can't be taken over")
+	}
+	return key, nil
+}
+
+// upgrade completes the handshake and takes over the connection.
+func upgrade(w http.ResponseWriter, key string) (*wsConn, io.Closer, error) {
+	conn, rw, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sum := sha1.Sum([]byte(key + wsGUID))
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Accept: %s\r\n\r\n", base64.StdEncoding.EncodeToString(sum[:]))
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return &wsConn{conn: conn, rw: rw}, conn, nil
+}
+
+func headerContains(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeFrame writes an unmasked final frame, as a server must.
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126)
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	if _, err := c.rw.Write(header); err != nil {
+		return err
+	}
+	if _, err := c.rw.Write(payload); err != nil {
+		return err
+	}
+	return c.rw.Flush()
+}
+
+// readFrame reads a client frame and unmasks its payload. It fails if the
+// client stays silent for wsReadTimeout.
+func (c *wsConn) readFrame() (byte, []byte, error) {
+	if err := c.conn.SetReadDeadline(time.Now().Add(wsReadTimeout)); err != nil {
+		return 0, nil, err
+	}
+	var head [2]byte
+	if _, err := io.ReadFull(c.rw, head[:]); err != nil {
+		return 0, nil, err
+	}
+	opcode := head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if !masked {
+		return 0, nil, errors.New("client frame is not masked")
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(c.rw, mask[:]); err != nil {
+		return 0, nil, err
+	}
+
+	if opcode >= wsOpClose {
+		if length > wsMaxControlPayload {
+			return 0, nil, errors.New("control frame too long")
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(c.rw, payload); err != nil {
+			return 0, nil, err
+		}
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+		return opcode, payload, nil
+	}
+	// the push stream doesn't take client data, skip it
+	if length > wsMaxDataPayload {
+		return 0, nil, errors.New("data frame too long")
+	}
+	if _, err := io.CopyN(io.Discard, c.rw, int64(length)); err != nil {
+		return 0, nil, err
+	}
+	return opcode, nil, nil
+}
+
+// handleWebSocket pushes the same messages as handleStream, one JSON text
+// message each. The resume point is only taken from the since query value.
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	key, err := handshakeKey(w, r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	backlog, sub, err := s.subscribe(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer sub.Close()
+
+	ws, conn, err := upgrade(w, key)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			opcode, payload, err := ws.readFrame()
+			if err != nil {
+				return
+			}
+			switch opcode {
+			case wsOpPing:
+				if ws.writeFrame(wsOpPong, payload) != nil {
+					return
+				}
+			case wsOpClose:
+				_ = ws.writeFrame(wsOpClose, payload)
+				return
+			}
+		}
+	}()
+
+	send := func(msg Message) error {
+		data, err := json.Marshal(newMessage(msg))
+		if err != nil {
+			return err
+		}
+		return ws.writeFrame(wsOpText, data)
+	}
+	if sub.Reset {
+		if err := ws.writeFrame(wsOpText, []byte(resetMessage)); err != nil {
+			return
+		}
+	}
+	for _, msg := range backlog {
+		if err := send(msg); err != nil {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case msg, ok := <-sub.C:
+			if !ok {
+				_ = ws.writeFrame(wsOpClose, nil)
+				return
+			}
+			if err := send(msg); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := ws.writeFrame(wsOpPing, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -3,20 +3,29 @@ import glob
 import pytest
 import sys
 
+# cases that follow every rule, so -strict must give the same output
+STRICT_CASES = ["../data/2", "../data/3"]
+
+@pytest.mark.parametrize("strict", [False, True], ids=["lenient", "strict"])
 @pytest.mark.parametrize("case", glob.glob("../data/*"))
-def test_case(tmp_path, case):
+def test_case(tmp_path, case, strict):
+    if strict and case not in STRICT_CASES:
+        pytest.skip("the sample breaks a rule, only the lenient run applies")
     cfg = f"{case}/config.json"
     events  = f"{case}/events"
     outlog = tmp_path / "out.log"
 
+    args = ["../bin/biathlon", "-config", cfg, "-events", events, "-out", str(outlog)]
+    if strict:
+        args.append("-strict")
     result = subprocess.run(
-        ["../bin/biathlon", "-config", cfg, "-events", events, "-out", str(outlog)],
+        args,
         stdout=subprocess.PIPE,
         stderr=sys.stderr,
         text=True,
         check=True,
     )
-    
+
     with open(f"{case}/expected.log") as f:
         expected_log = f.read().strip()
     actual_log = outlog.read_text().strip()
@@ -25,4 +34,14 @@ def test_case(tmp_path, case):
     with open(f"{case}/expected.out") as f:
         expected_out = f.read().strip()
     actual_out = result.stdout.strip()
-    assert actual_out == expected_out
\ No newline at end of file
+    assert actual_out == expected_out
+
+def test_strict_rejects_sample(tmp_path):
+    result = subprocess.run(
+        ["../bin/biathlon", "-config", "../data/1/config.json", "-events", "../data/1/events",
+         "-out", str(tmp_path / "out.log"), "-strict"],
+        stderr=subprocess.PIPE,
+        text=True,
+    )
+    assert result.returncode != 0
+    assert "lap 1 has 1 firing range visits, expected 2" in result.stderr